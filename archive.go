@@ -0,0 +1,132 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// archiveExts lists the filename extensions supported by writeArchive.
+var archiveExts = []string{".zip", ".tar.gz", ".tgz"}
+
+// validArchivePath returns true if p has one of archiveExts.
+func validArchivePath(p string) bool {
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(p, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeArchive writes the files that writeOutputDir would write for reps into a single
+// zip or gzipped tar archive at p, along with any raw API responses and screenshots
+// (see writeArchiveFiles). The format is chosen based on p's extension.
+func writeArchive(p string, reps []*report, cfg *reportConfig) error {
+	if !validArchivePath(p) {
+		return fmt.Errorf("unsupported archive extension (want one of %s)", strings.Join(archiveExts, " "))
+	}
+	return writeFileAtomic(p, func(w io.Writer) error {
+		if strings.HasSuffix(p, ".zip") {
+			return writeZip(w, reps, cfg)
+		}
+		return writeTarGz(w, reps, cfg)
+	})
+}
+
+// writeZip writes a zip archive containing reps' output files to w.
+func writeZip(w io.Writer, reps []*report, cfg *reportConfig) error {
+	zw := zip.NewWriter(w)
+	if err := writeArchiveFiles(reps, cfg, func(name string, fn func(w io.Writer) error) error {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: cfg.startTime,
+		})
+		if err != nil {
+			return err
+		}
+		return fn(fw)
+	}); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// writeTarGz writes a gzipped tar archive containing reps' output files to w.
+func writeTarGz(w io.Writer, reps []*report, cfg *reportConfig) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	if err := writeArchiveFiles(reps, cfg, func(name string, fn func(w io.Writer) error) error {
+		// tar headers contain the file's size, so each file needs to be buffered.
+		var b bytes.Buffer
+		if err := fn(&b); err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(b.Len()),
+			ModTime: cfg.startTime,
+		}); err != nil {
+			return err
+		}
+		_, err := tw.Write(b.Bytes())
+		return err
+	}); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// writeArchiveFiles calls create with the name of each file that should be included in
+// an archive for reps and a function that writes the file's contents. In addition to
+// the files from writeOutputFiles, raw API responses and screenshots that were written
+// for reps during this run (see getReport) are included in "raw" and "screenshots"
+// subdirectories.
+func writeArchiveFiles(reps []*report, cfg *reportConfig,
+	create func(name string, fn func(w io.Writer) error) error) error {
+	if err := writeOutputFiles(reps, cfg, create); err != nil {
+		return err
+	}
+
+	// Copies the file at p into the archive as name.
+	add := func(p, name string) error {
+		return create(name, func(w io.Writer) error {
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(w, f)
+			return err
+		})
+	}
+
+	// Reports loaded from -cache or that couldn't be fetched don't have files.
+	for _, rep := range reps {
+		if rep.rawPath != "" {
+			if err := add(rep.rawPath, "raw/"+filepath.Base(rep.rawPath)); err != nil {
+				return err
+			}
+		}
+		if rep.screenshotPath != "" {
+			if err := add(rep.screenshotPath, "screenshots/"+filepath.Base(rep.screenshotPath)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// reportCacheFormat should be incremented whenever the report struct changes
+// in a way that would make previously-cached reports unusable.
+const reportCacheFormat = 3
+
+// reportCache stores parsed reports on disk so that they can be reused by later runs
+// without calling the API or parsing its response again.
+type reportCache struct {
+	dir     string        // empty to disable caching
+	ttl     time.Duration // maximum age of cached reports (0 for no limit)
+	version string        // version stamp included in keys; see toolVersion
+	now     func() time.Time
+}
+
+func newReportCache(dir string, ttl time.Duration) *reportCache {
+	return &reportCache{dir: dir, ttl: ttl, version: toolVersion(), now: time.Now}
+}
+
+// path returns the path of the file holding the cached report for u.
+// Settings that affect the report's contents are included in the key.
+func (c *reportCache) path(u string, cfg *reportConfig) string {
+	key := strings.Join([]string{
+		fmt.Sprintf("format=%d", reportCacheFormat),
+		"version=" + c.version,
+		"url=" + u,
+		"strategy=" + strategy(cfg),
+		"categories=" + strings.Join(requestedCategories(cfg), ","),
+		fmt.Sprintf("detailSizes=%v", cfg.detailSizes),
+		fmt.Sprintf("filmstrip=%v", cfg.filmstrip),
+		fmt.Sprintf("strict=%v", cfg.strict),
+	}, "\n")
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// get returns the cached report for u, or nil if there isn't an unexpired one.
+func (c *reportCache) get(u string, cfg *reportConfig) *report {
+	if c.dir == "" {
+		return nil
+	}
+	p := c.path(u, cfg)
+	fi, err := os.Stat(p)
+	if err != nil || (c.ttl > 0 && c.now().Sub(fi.ModTime()) > c.ttl) {
+		return nil
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil
+	}
+	var rep report
+	if err := json.Unmarshal(b, &rep); err != nil {
+		return nil
+	}
+	return &rep
+}
+
+// put saves rep as the cached report for u.
+func (c *reportCache) put(u string, rep *report, cfg *reportConfig) error {
+	if c.dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	return writeFile(c.path(u, cfg), func(w io.Writer) error { return json.NewEncoder(w).Encode(rep) })
+}
+
+// toolVersion returns a string identifying the running build of this program,
+// or an empty string if build information is unavailable.
+func toolVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	ver := info.Main.Version
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" || s.Key == "vcs.modified" {
+			ver += " " + s.Key + "=" + s.Value
+		}
+	}
+	return ver
+}
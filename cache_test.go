@@ -0,0 +1,47 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReportCache(t *testing.T) {
+	const u = "https://example.org/"
+	now := time.Now()
+	c := newReportCache(t.TempDir(), time.Hour)
+	c.now = func() time.Time { return now }
+
+	cfg := reportConfig{}
+	if rep := c.get(u, &cfg); rep != nil {
+		t.Fatalf("get(%q) on empty cache = %+v; want nil", u, rep)
+	}
+	want := &report{URL: u, Categories: []category{{ID: "performance", Title: "Performance", Score: 85}}}
+	if err := c.put(u, want, &cfg); err != nil {
+		t.Fatal("put failed: ", err)
+	}
+	if got := c.get(u, &cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("get(%q) = %+v; want %+v", u, got, want)
+	}
+
+	// Settings that affect reports should be part of the key.
+	if rep := c.get(u, &reportConfig{mobile: true}); rep != nil {
+		t.Errorf("get(%q) with different strategy = %+v; want nil", u, rep)
+	}
+
+	// So should the version.
+	c.version = "v2.0.0"
+	if rep := c.get(u, &cfg); rep != nil {
+		t.Errorf("get(%q) with different version = %+v; want nil", u, rep)
+	}
+	c.version = toolVersion()
+
+	// Expired reports shouldn't be returned.
+	c.now = func() time.Time { return now.Add(2 * time.Hour) }
+	if rep := c.get(u, &cfg); rep != nil {
+		t.Errorf("get(%q) after TTL = %+v; want nil", u, rep)
+	}
+}
@@ -0,0 +1,311 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// readBaseline reads previously-saved reports from the JSON file at p.
+// The file can contain either an array of reports (as written by writeBaseline)
+// or a document written by writeJSON.
+func readBaseline(p string) ([]*report, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	if t := bytes.TrimSpace(b); len(t) > 0 && t[0] == '{' {
+		var out jsonOutput
+		if err := json.Unmarshal(b, &out); err != nil {
+			return nil, err
+		}
+		return out.Reports, nil
+	}
+	var reps []*report
+	if err := json.Unmarshal(b, &reps); err != nil {
+		return nil, err
+	}
+	return reps, nil
+}
+
+// writeBaseline writes reps to a JSON file at p that can be read by readBaseline.
+func writeBaseline(p string, reps []*report) error {
+	return writeFile(p, func(w io.Writer) error { return json.NewEncoder(w).Encode(reps) })
+}
+
+// mergeBaseline returns reps with failed reports replaced by the
+// corresponding reports from base, if present.
+func mergeBaseline(base, reps []*report) []*report {
+	old := make(map[string]*report, len(base))
+	for _, rep := range base {
+		old[rep.URL] = rep
+	}
+	merged := make([]*report, len(reps))
+	for i, rep := range reps {
+		if o, ok := old[rep.URL]; ok && len(rep.Categories) == 0 {
+			merged[i] = o
+		} else {
+			merged[i] = rep
+		}
+	}
+	return merged
+}
+
+// baselineReports returns the reports in cfg.baseline keyed by URL.
+func baselineReports(cfg *reportConfig) map[string]*report {
+	base := make(map[string]*report, len(cfg.baseline))
+	for _, rep := range cfg.baseline {
+		base[rep.URL] = rep
+	}
+	return base
+}
+
+// formatDelta returns a string like "(+3)" or "(-4)" describing the change
+// from before to after.
+func formatDelta(before, after int) string {
+	return fmt.Sprintf("(%+d)", after-before)
+}
+
+// auditChange describes an audit whose score differs from the baseline.
+type auditChange struct {
+	ID       string
+	Title    string
+	Category string // ID of first category containing the audit
+	Before   int    // [0, 100] or -1 if unset
+	After    int    // [0, 100] or -1 if unset
+
+	// Whether the audit failed according to auditFailed, which uses unrounded
+	// scores: a drop from 1 to 0.996 fails even though both round to 100.
+	failedBefore, failedAfter bool
+}
+
+// kind returns a short description of the change, e.g. "newly failing".
+func (ch *auditChange) kind() string {
+	before, after := ch.failedBefore, ch.failedAfter
+	switch {
+	case !before && after:
+		return "newly failing"
+	case before && !after:
+		return "newly passing"
+	}
+	return "score changed"
+}
+
+// diffAudits returns the audits in rep whose scores or pass/fail states differ
+// from the same audits (matched by ID) in base. Audits that are only present
+// in one of the reports are ignored.
+func diffAudits(base, rep *report) []auditChange {
+	before := make(map[string]audit)
+	for _, cat := range base.Categories {
+		for _, aud := range cat.Audits {
+			before[aud.ID] = aud
+		}
+	}
+	var changes []auditChange
+	seen := make(map[string]struct{})
+	for _, cat := range rep.Categories {
+		for _, aud := range cat.Audits {
+			if _, ok := seen[aud.ID]; ok {
+				continue
+			}
+			seen[aud.ID] = struct{}{}
+			baud, ok := before[aud.ID]
+			if !ok {
+				continue
+			}
+			ch := auditChange{
+				ID:           aud.ID,
+				Title:        aud.Title,
+				Category:     cat.ID,
+				Before:       baud.Score,
+				After:        aud.Score,
+				failedBefore: auditFailed(&baud),
+				failedAfter:  auditFailed(&aud),
+			}
+			if ch.Before != ch.After || ch.failedBefore != ch.failedAfter {
+				changes = append(changes, ch)
+			}
+		}
+	}
+	return changes
+}
+
+// Formats for -diff-format.
+const (
+	diffTable   = "table"
+	diffJSON    = "json"
+	diffUnified = "unified"
+)
+
+// urlAuditChanges holds the changed audits for a single URL.
+type urlAuditChanges struct {
+	rep     *report
+	changes []auditChange
+}
+
+// findAuditChanges returns the audits in reps whose scores changed relative to
+// the corresponding reports in cfg.baseline. URLs without changes are omitted.
+func findAuditChanges(reps []*report, cfg *reportConfig) []urlAuditChanges {
+	base := baselineReports(cfg)
+	var all []urlAuditChanges
+	for _, rep := range reps {
+		brep, ok := base[rep.URL]
+		if !ok || len(rep.Categories) == 0 {
+			continue
+		}
+		if changes := diffAudits(brep, rep); len(changes) > 0 {
+			all = append(all, urlAuditChanges{rep, changes})
+		}
+	}
+	return all
+}
+
+// newFailures returns descriptions of audits in reps that passed in the
+// corresponding reports in cfg.baseline but now fail.
+func newFailures(reps []*report, cfg *reportConfig) []string {
+	var descs []string
+	for _, uc := range findAuditChanges(reps, cfg) {
+		for _, ch := range uc.changes {
+			if ch.kind() == "newly failing" {
+				descs = append(descs, fmt.Sprintf("%v: %s [%s] (%s -> %s)", stripUserinfo(uc.rep.URL),
+					ch.Title, ch.ID, auditScoreString(ch.Before), auditScoreString(ch.After)))
+			}
+		}
+	}
+	return descs
+}
+
+// writeAuditDiff writes a description of the audits in reps whose scores
+// changed relative to the corresponding reports in cfg.baseline.
+// The output's format is determined by cfg.diffFormat.
+func writeAuditDiff(w io.Writer, reps []*report, cfg *reportConfig) error {
+	all := findAuditChanges(reps, cfg)
+	switch cfg.diffFormat {
+	case diffJSON:
+		return writeDiffJSON(w, reps, all, cfg)
+	case diffUnified:
+		return writeAuditDiffUnified(w, all, cfg)
+	}
+
+	fmt.Fprintln(w, "Audit changes since baseline:")
+	for _, uc := range all {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, displayURL(uc.rep.URL, cfg))
+		var rows [][]string
+		for _, ch := range uc.changes {
+			rows = append(rows, []string{
+				ch.kind(),
+				auditScoreString(ch.Before),
+				"->",
+				auditScoreString(ch.After),
+				fmt.Sprintf("%s [%s]", ch.Title, ch.ID),
+			})
+		}
+		for _, ln := range formatTable(rows, tableSpacing(1), tableRightCol(1), tableRightCol(3)) {
+			fmt.Fprintf(w, "    %s\n", ln)
+		}
+	}
+	return nil
+}
+
+// diffDoc is the JSON document written for -diff-format json.
+type diffDoc struct {
+	Categories []categoryDiffEntry `json:"categories"`
+	Audits     []auditDiffEntry    `json:"audits"`
+}
+
+// categoryDiffEntry is a JSON representation of a category score's change
+// relative to the baseline, as shown by the deltas in the text summary.
+type categoryDiffEntry struct {
+	URL      string `json:"url"`
+	Category string `json:"category"`
+	Before   int    `json:"before"`
+	After    int    `json:"after"`
+	Delta    int    `json:"delta"`
+}
+
+// auditDiffEntry is a JSON representation of an auditChange.
+type auditDiffEntry struct {
+	URL      string `json:"url"`
+	Category string `json:"category"`
+	Audit    string `json:"audit"`
+	Title    string `json:"title"`
+	Before   int    `json:"before"` // -1 if unset
+	After    int    `json:"after"`  // -1 if unset
+	Delta    int    `json:"delta"`  // 0 if either score is unset
+	Change   string `json:"change"` // e.g. "newly failing"
+}
+
+// writeDiffJSON writes a diffDoc to w describing the category score changes of reps
+// relative to cfg.baseline and the supplied audit changes.
+func writeDiffJSON(w io.Writer, reps []*report, all []urlAuditChanges, cfg *reportConfig) error {
+	doc := diffDoc{Categories: make([]categoryDiffEntry, 0), Audits: make([]auditDiffEntry, 0)}
+	base := baselineReports(cfg)
+	for _, rep := range reps {
+		brep, ok := base[rep.URL]
+		if !ok {
+			continue
+		}
+		for _, cat := range rep.Categories {
+			if bc := findCategory(brep, cat.ID); bc != nil && bc.Score >= 0 && cat.Score >= 0 {
+				doc.Categories = append(doc.Categories, categoryDiffEntry{
+					URL:      stripUserinfo(rep.URL),
+					Category: cat.ID,
+					Before:   bc.Score,
+					After:    cat.Score,
+					Delta:    cat.Score - bc.Score,
+				})
+			}
+		}
+	}
+	for _, uc := range all {
+		for _, ch := range uc.changes {
+			ent := auditDiffEntry{
+				URL:      stripUserinfo(uc.rep.URL),
+				Category: ch.Category,
+				Audit:    ch.ID,
+				Title:    ch.Title,
+				Before:   ch.Before,
+				After:    ch.After,
+				Change:   ch.kind(),
+			}
+			if ch.Before >= 0 && ch.After >= 0 {
+				ent.Delta = ch.After - ch.Before
+			}
+			doc.Audits = append(doc.Audits, ent)
+		}
+	}
+	return newJSONEncoder(w, cfg).Encode(doc)
+}
+
+// writeAuditDiffUnified writes the supplied changes to w in a format resembling
+// a unified diff, with each changed audit's old and new scores on "-" and "+" lines.
+func writeAuditDiffUnified(w io.Writer, all []urlAuditChanges, cfg *reportConfig) error {
+	if len(all) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w, "--- baseline")
+	fmt.Fprintln(w, "+++ current")
+	for _, uc := range all {
+		fmt.Fprintf(w, "@@ %s @@\n", displayURL(uc.rep.URL, cfg))
+		for _, ch := range uc.changes {
+			fmt.Fprintf(w, "-%3s %s [%s]\n", auditScoreString(ch.Before), ch.Title, ch.ID)
+			fmt.Fprintf(w, "+%3s %s [%s]\n", auditScoreString(ch.After), ch.Title, ch.ID)
+		}
+	}
+	return nil
+}
+
+// auditScoreString formats an audit score, using "." for unset scores.
+func auditScoreString(score int) string {
+	if score < 0 {
+		return "."
+	}
+	return strconv.Itoa(score)
+}
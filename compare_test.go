@@ -0,0 +1,92 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffAudits(t *testing.T) {
+	base := newTestAuditReport("https://example.org/", 0, map[string]int{"a": 100, "b": 50, "c": 80, "d": 90})
+	rep := newTestAuditReport("https://example.org/", 0, map[string]int{"a": 40, "b": 100, "c": 70, "d": 90, "e": 0})
+	want := []auditChange{
+		{ID: "a", Title: "Audit a", Category: "performance", Before: 100, After: 40, failedAfter: true},
+		{ID: "b", Title: "Audit b", Category: "performance", Before: 50, After: 100, failedBefore: true},
+		{ID: "c", Title: "Audit c", Category: "performance", Before: 80, After: 70, failedBefore: true, failedAfter: true},
+	}
+	got := diffAudits(base, rep)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("diffAudits() = %+v; want %+v", got, want)
+	}
+	for i, kind := range []string{"newly failing", "newly passing", "score changed"} {
+		if k := got[i].kind(); k != kind {
+			t.Errorf("%v kind() = %q; want %q", got[i].ID, k, kind)
+		}
+	}
+}
+
+func TestNewFailures(t *testing.T) {
+	cfg := reportConfig{baseline: []*report{
+		newTestAuditReport("https://example.org/x", 0, map[string]int{"a": 100, "b": 50}),
+		newTestAuditReport("https://example.org/y", 0, map[string]int{"a": 100, "b": 100}),
+	}}
+	reps := []*report{
+		newTestAuditReport("https://example.org/x", 0, map[string]int{"a": 100, "b": 30}), // already failing, so not new
+		newTestAuditReport("https://example.org/y", 0, map[string]int{"a": 90, "b": 100}),
+		newTestAuditReport("https://example.org/z", 0, map[string]int{"a": 0, "b": 0}), // not in baseline
+	}
+	// Rounding shouldn't hide a failure.
+	reps[0].Categories[0].Audits[0].ScoreFloat = 0.996
+	want := []string{
+		"https://example.org/x: Audit a [a] (100 -> 100)",
+		"https://example.org/y: Audit a [a] (100 -> 90)",
+	}
+	if got := newFailures(reps, &cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("newFailures() = %q; want %q", got, want)
+	}
+}
+
+func TestWriteAuditDiff_Formats(t *testing.T) {
+	reps := []*report{newTestAuditReport("https://example.org/x", 70, map[string]int{"a": 40, "b": 90}), {URL: "https://example.org/failed"}}
+	base := []*report{newTestAuditReport("https://example.org/x", 80, map[string]int{"a": 100, "b": 90}), newTestAuditReport("https://example.org/x", 80, map[string]int{"a": 100, "b": 90})}
+	base[1].URL = "https://example.org/failed"
+
+	for _, tc := range []struct {
+		format string
+		want   []string
+	}{
+		{diffTable, []string{
+			"Audit changes since baseline:",
+			"",
+			"/x",
+			"    newly failing 100 -> 40 Audit a [a]",
+			"",
+		}},
+		{diffJSON, []string{
+			`{"categories":[{"url":"https://example.org/x","category":"performance","before":80,"after":70,"delta":-10}],` +
+				`"audits":[{"url":"https://example.org/x","category":"performance","audit":"a","title":"Audit a",` +
+				`"before":100,"after":40,"delta":-60,"change":"newly failing"}]}`,
+			"",
+		}},
+		{diffUnified, []string{
+			"--- baseline",
+			"+++ current",
+			"@@ /x @@",
+			"-100 Audit a [a]",
+			"+ 40 Audit a [a]",
+			"",
+		}},
+	} {
+		var b bytes.Buffer
+		cfg := reportConfig{baseline: base, diffFormat: tc.format}
+		if err := writeAuditDiff(&b, reps, &cfg); err != nil {
+			t.Errorf("writeAuditDiff with %q failed: %v", tc.format, err)
+		} else if got, want := b.String(), strings.Join(tc.want, "\n"); got != want {
+			t.Errorf("writeAuditDiff with %q wrote:\n%s\nwant:\n%s", tc.format, got, want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	pso "google.golang.org/api/pagespeedonline/v5"
+)
+
+// fieldData contains real-user metrics from the Chrome UX Report.
+type fieldData struct {
+	Overall        string        `json:"overall,omitempty"`        // e.g. "FAST", "AVERAGE", or "SLOW"
+	OriginFallback bool          `json:"originFallback,omitempty"` // page data unavailable; origin data used instead
+	Metrics        []fieldMetric `json:"metrics"`
+}
+
+// fieldMetric is a single metric from fieldData.
+type fieldMetric struct {
+	Name       string  `json:"name"`       // short name, e.g. "lcp"
+	Percentile float64 `json:"percentile"` // 75th percentile in milliseconds (or unitless for CLS)
+	Category   string  `json:"category"`   // e.g. "FAST", "AVERAGE", or "SLOW"
+}
+
+// fieldMetricKeys maps from short names of the field metrics that are extracted
+// from reports to the keys used by the API, in the order in which they're reported.
+var fieldMetricKeys = []struct{ name, key string }{
+	{"fcp", "FIRST_CONTENTFUL_PAINT_MS"},
+	{"lcp", "LARGEST_CONTENTFUL_PAINT_MS"},
+	{"cls", "CUMULATIVE_LAYOUT_SHIFT_SCORE"},
+	{"inp", "INTERACTION_TO_NEXT_PAINT"},
+}
+
+// getFieldData returns the field metrics from le, or nil if none are available
+// (typically because the page or origin doesn't get enough traffic).
+func getFieldData(le *pso.PagespeedApiLoadingExperienceV5) *fieldData {
+	if le == nil {
+		return nil
+	}
+	fd := fieldData{Overall: le.OverallCategory, OriginFallback: le.OriginFallback}
+	for _, mk := range fieldMetricKeys {
+		m, ok := le.Metrics[mk.key]
+		if !ok {
+			continue
+		}
+		pct := float64(m.Percentile)
+		if mk.name == "cls" {
+			pct /= 100 // the API reports CLS multiplied by 100
+		}
+		fd.Metrics = append(fd.Metrics, fieldMetric{Name: mk.name, Percentile: pct, Category: m.Category})
+	}
+	if len(fd.Metrics) == 0 {
+		return nil
+	}
+	return &fd
+}
+
+// writeFieldData writes a section to w describing rep's field data.
+func writeFieldData(w io.Writer, rep *report, cfg *reportConfig) {
+	fmt.Fprintln(w, "Field data")
+	fmt.Fprintln(w, strings.Repeat("-", catUnderlineLen))
+	if rep.FieldData == nil && rep.OriginFieldData == nil {
+		fmt.Fprintln(w, "No field data available")
+		fmt.Fprintln(w)
+		return
+	}
+	var rows [][]string
+	add := func(label string, fd *fieldData) {
+		if fd == nil {
+			return
+		}
+		if fd.OriginFallback {
+			label += " (origin fallback)"
+		}
+		rows = append(rows, []string{label, "", fd.Overall})
+		for _, m := range fd.Metrics {
+			rows = append(rows, []string{"  " + strings.ToUpper(m.Name), formatMetric(m.Name, m.Percentile), m.Category})
+		}
+	}
+	add("Page", rep.FieldData)
+	add("Origin", rep.OriginFieldData)
+	for _, ln := range formatTable(rows, append(textTableOpts(cfg), tableRightCol(1))...) {
+		fmt.Fprintln(w, ln)
+	}
+	fmt.Fprintln(w)
+}
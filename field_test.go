@@ -0,0 +1,70 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	pso "google.golang.org/api/pagespeedonline/v5"
+)
+
+func TestGetFieldData(t *testing.T) {
+	le := &pso.PagespeedApiLoadingExperienceV5{
+		OverallCategory: "AVERAGE",
+		Metrics: map[string]pso.UserPageLoadMetricV5{
+			"LARGEST_CONTENTFUL_PAINT_MS":     {Percentile: 2800, Category: "AVERAGE"},
+			"CUMULATIVE_LAYOUT_SHIFT_SCORE":   {Percentile: 5, Category: "FAST"},
+			"FIRST_CONTENTFUL_PAINT_MS":       {Percentile: 1200, Category: "FAST"},
+			"EXPERIMENTAL_TIME_TO_FIRST_BYTE": {Percentile: 600, Category: "AVERAGE"},
+		},
+	}
+	want := &fieldData{Overall: "AVERAGE", Metrics: []fieldMetric{
+		{"fcp", 1200, "FAST"},
+		{"lcp", 2800, "AVERAGE"},
+		{"cls", 0.05, "FAST"},
+	}}
+	if got := getFieldData(le); !reflect.DeepEqual(got, want) {
+		t.Errorf("getFieldData() = %+v; want %+v", got, want)
+	}
+
+	// Sites without enough traffic have no metrics.
+	for _, le := range []*pso.PagespeedApiLoadingExperienceV5{nil, {Id: "https://example.org/"}} {
+		if got := getFieldData(le); got != nil {
+			t.Errorf("getFieldData(%+v) = %+v; want nil", le, got)
+		}
+	}
+}
+
+func TestWriteFieldData(t *testing.T) {
+	rep := &report{
+		URL: "https://example.org/",
+		FieldData: &fieldData{Overall: "FAST", OriginFallback: true, Metrics: []fieldMetric{
+			{"lcp", 1800, "FAST"},
+			{"cls", 0.12, "AVERAGE"},
+		}},
+	}
+	var b bytes.Buffer
+	writeFieldData(&b, rep, &reportConfig{})
+	want := strings.Join([]string{
+		"Field data",
+		strings.Repeat("-", catUnderlineLen),
+		"Page (origin fallback)           FAST",
+		"  LCP                   1800 ms  FAST",
+		"  CLS                     0.120  AVERAGE",
+		"",
+		"",
+	}, "\n")
+	if got := b.String(); got != want {
+		t.Errorf("writeFieldData wrote:\n%s\nwant:\n%s", got, want)
+	}
+
+	b.Reset()
+	writeFieldData(&b, &report{URL: "https://example.org/"}, &reportConfig{})
+	if got, want := b.String(), "No field data available\n"; !strings.Contains(got, want) {
+		t.Errorf("writeFieldData wrote %q; want %q", got, want)
+	}
+}
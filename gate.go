@@ -0,0 +1,216 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// gateExpr is a boolean expression over category scores supplied via -gate-expr,
+// e.g. "performance>=80 && (seo>=90 || accessibility>=95)".
+type gateExpr interface {
+	// eval evaluates the expression using scores, keyed by category ID.
+	// Comparisons involving missing categories are false.
+	eval(scores map[string]int) bool
+}
+
+type gateOr struct{ a, b gateExpr }
+type gateAnd struct{ a, b gateExpr }
+type gateNot struct{ e gateExpr }
+type gateCmp struct {
+	cat string // category ID
+	op  string // e.g. ">="
+	val int
+}
+
+func (e gateOr) eval(scores map[string]int) bool  { return e.a.eval(scores) || e.b.eval(scores) }
+func (e gateAnd) eval(scores map[string]int) bool { return e.a.eval(scores) && e.b.eval(scores) }
+func (e gateNot) eval(scores map[string]int) bool { return !e.e.eval(scores) }
+
+func (e gateCmp) eval(scores map[string]int) bool {
+	score, ok := scores[e.cat]
+	if !ok {
+		return false
+	}
+	switch e.op {
+	case ">=":
+		return score >= e.val
+	case ">":
+		return score > e.val
+	case "<=":
+		return score <= e.val
+	case "<":
+		return score < e.val
+	case "==":
+		return score == e.val
+	case "!=":
+		return score != e.val
+	}
+	return false
+}
+
+// gateOps lists comparison operators, with longer operators first.
+var gateOps = []string{">=", "<=", "==", "!=", ">", "<"}
+
+// tokenizeGateExpr splits s into identifiers, numbers, operators, and parentheses.
+func tokenizeGateExpr(s string) ([]string, error) {
+	var toks []string
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(' || c == ')':
+			toks = append(toks, string(c))
+			i++
+		case strings.HasPrefix(s[i:], "&&") || strings.HasPrefix(s[i:], "||"):
+			toks = append(toks, s[i:i+2])
+			i += 2
+		case unicode.IsLetter(c) || unicode.IsDigit(c):
+			j := i
+			for j < len(s) && (unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j])) || s[j] == '-') {
+				j++
+			}
+			toks = append(toks, s[i:j])
+			i = j
+		default:
+			var op string
+			for _, o := range gateOps {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				if c == '!' {
+					op = "!"
+				} else {
+					return nil, fmt.Errorf("unexpected %q at position %d", c, i)
+				}
+			}
+			toks = append(toks, op)
+			i += len(op)
+		}
+	}
+	return toks, nil
+}
+
+// parseGateExpr parses a -gate-expr flag value. The grammar is:
+//
+//	expr  = and { "||" and }
+//	and   = unary { "&&" unary }
+//	unary = "!" unary | "(" expr ")" | category op number
+//	op    = ">=" | ">" | "<=" | "<" | "==" | "!="
+func parseGateExpr(s string) (gateExpr, error) {
+	toks, err := tokenizeGateExpr(s)
+	if err != nil {
+		return nil, err
+	}
+	p := gateParser{toks: toks}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("unexpected %q", p.toks[p.pos])
+	}
+	return e, nil
+}
+
+// gateParser is a recursive-descent parser used by parseGateExpr.
+type gateParser struct {
+	toks []string
+	pos  int
+}
+
+// next returns the next token without consuming it, or an empty string at the end.
+func (p *gateParser) next() string {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return ""
+}
+
+func (p *gateParser) parseOr() (gateExpr, error) {
+	e, err := p.parseAnd()
+	for err == nil && p.next() == "||" {
+		p.pos++
+		var b gateExpr
+		if b, err = p.parseAnd(); err == nil {
+			e = gateOr{e, b}
+		}
+	}
+	return e, err
+}
+
+func (p *gateParser) parseAnd() (gateExpr, error) {
+	e, err := p.parseUnary()
+	for err == nil && p.next() == "&&" {
+		p.pos++
+		var b gateExpr
+		if b, err = p.parseUnary(); err == nil {
+			e = gateAnd{e, b}
+		}
+	}
+	return e, err
+}
+
+func (p *gateParser) parseUnary() (gateExpr, error) {
+	switch tok := p.next(); tok {
+	case "":
+		return nil, errors.New("unexpected end of expression")
+	case "!":
+		p.pos++
+		e, err := p.parseUnary()
+		return gateNot{e}, err
+	case "(":
+		p.pos++
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, errors.New("missing ')'")
+		}
+		p.pos++
+		return e, nil
+	default:
+		if !knownCategory(tok) {
+			return nil, fmt.Errorf("unknown category %q", tok)
+		}
+		if p.pos+2 >= len(p.toks) {
+			return nil, fmt.Errorf("incomplete comparison for %q", tok)
+		}
+		op, num := p.toks[p.pos+1], p.toks[p.pos+2]
+		valid := false
+		for _, o := range gateOps {
+			valid = valid || op == o
+		}
+		if !valid {
+			return nil, fmt.Errorf("bad operator %q after %q", op, tok)
+		}
+		val, err := strconv.Atoi(num)
+		if err != nil {
+			return nil, fmt.Errorf("bad score %q for %q", num, tok)
+		}
+		p.pos += 3
+		return gateCmp{tok, op, val}, nil
+	}
+}
+
+// reportScores returns rep's category scores keyed by category ID.
+// Unset scores are omitted.
+func reportScores(rep *report) map[string]int {
+	scores := make(map[string]int, len(rep.Categories))
+	for _, cat := range rep.Categories {
+		if cat.Score >= 0 {
+			scores[cat.ID] = cat.Score
+		}
+	}
+	return scores
+}
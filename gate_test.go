@@ -0,0 +1,50 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import "testing"
+
+func TestParseGateExpr(t *testing.T) {
+	const expr = "performance>=80 && (seo>=90 || accessibility>=95)"
+	for _, tc := range []struct {
+		expr   string
+		scores map[string]int
+		want   bool
+	}{
+		{expr, map[string]int{"performance": 80, "seo": 90, "accessibility": 50}, true},
+		{expr, map[string]int{"performance": 80, "seo": 50, "accessibility": 95}, true},
+		{expr, map[string]int{"performance": 79, "seo": 100, "accessibility": 100}, false},
+		{expr, map[string]int{"performance": 90, "seo": 89, "accessibility": 94}, false},
+		{expr, map[string]int{"seo": 100}, false}, // missing categories fail comparisons
+		{"best-practices == 100", map[string]int{"best-practices": 100}, true},
+		{"!(pwa < 50)", map[string]int{"pwa": 50}, true},
+		{"seo>90 || seo<10 && performance!=0", map[string]int{"seo": 5, "performance": 0}, false},
+	} {
+		e, err := parseGateExpr(tc.expr)
+		if err != nil {
+			t.Errorf("parseGateExpr(%q) failed: %v", tc.expr, err)
+		} else if got := e.eval(tc.scores); got != tc.want {
+			t.Errorf("parseGateExpr(%q).eval(%v) = %v; want %v", tc.expr, tc.scores, got, tc.want)
+		}
+	}
+}
+
+func TestParseGateExpr_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"performance",
+		"performance>=",
+		"performance>=abc",
+		"performance=80",
+		"speed>=80",
+		"(seo>=90",
+		"seo>=90)",
+		"seo>=90 &&",
+		"seo>=90 & performance>=80",
+	} {
+		if _, err := parseGateExpr(expr); err == nil {
+			t.Errorf("parseGateExpr(%q) unexpectedly succeeded", expr)
+		}
+	}
+}
@@ -3,6 +3,7 @@ module github.com/derat/check-page-speed
 go 1.18
 
 require (
+	golang.org/x/term v0.0.0-20220722155259-a9ba230a4035
 	google.golang.org/api v0.92.0
 	gopkg.in/gomail.v2 v2.0.0-20160411212932-81ebce5c23df
 )
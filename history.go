@@ -0,0 +1,206 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// historyEntry contains the category scores from a single run for a single URL.
+// Each URL's history is stored as a file containing one JSON-marshaled entry per line.
+type historyEntry struct {
+	Time   time.Time      `json:"time"`
+	URL    string         `json:"url,omitempty"`
+	Scores map[string]int `json:"scores"`          // keyed by category ID
+	Label  string         `json:"label,omitempty"` // from -label
+}
+
+// historyPath returns the path of the history file for u within dir.
+func historyPath(dir, u string, cfg *reportConfig) string {
+	return filepath.Join(dir, urlFilename(u)+"-"+strategy(cfg)+".jsonl")
+}
+
+// readHistory reads the entries from the history file at p.
+// An empty slice is returned if the file doesn't exist.
+func readHistory(p string) ([]historyEntry, error) {
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []historyEntry
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var ent historyEntry
+		if err := json.Unmarshal(sc.Bytes(), &ent); err != nil {
+			return nil, fmt.Errorf("%v: %v", p, err)
+		}
+		entries = append(entries, ent)
+	}
+	return entries, sc.Err()
+}
+
+// readLastHistory returns the most recent history entry within dir for each of
+// the supplied reports, keyed by URL. URLs without history are omitted.
+func readLastHistory(dir string, reps []*report, cfg *reportConfig) (map[string]historyEntry, error) {
+	last := make(map[string]historyEntry)
+	for _, rep := range reps {
+		entries, err := readHistory(historyPath(dir, rep.URL, cfg))
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) > 0 {
+			last[rep.URL] = entries[len(entries)-1]
+		}
+	}
+	return last, nil
+}
+
+// appendHistory appends an entry for each of the supplied reports to its
+// history file within dir. Failed reports are skipped.
+func appendHistory(dir string, reps []*report, cfg *reportConfig) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, rep := range reps {
+		if len(rep.Categories) == 0 {
+			continue
+		}
+		ent := historyEntry{Time: cfg.startTime, URL: stripUserinfo(rep.URL), Scores: make(map[string]int), Label: cfg.label}
+		for _, cat := range rep.Categories {
+			ent.Scores[cat.ID] = cat.Score
+		}
+		b, err := json.Marshal(&ent)
+		if err != nil {
+			return err
+		}
+		f, err := os.OpenFile(historyPath(dir, rep.URL, cfg), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(append(b, '\n')); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortByDelta stably sorts reps in descending order by the absolute change in the
+// score of the category with the supplied ID since the entries in last.
+// Ties are ordered by URL, and reports without scores to compare are placed last.
+func sortByDelta(reps []*report, last map[string]historyEntry, id string) {
+	delta := func(rep *report) int {
+		cat := findCategory(rep, id)
+		if cat == nil || cat.Score < 0 {
+			return -1
+		}
+		prev, ok := last[rep.URL].Scores[id]
+		if !ok || prev < 0 {
+			return -1
+		}
+		if d := cat.Score - prev; d < 0 {
+			return -d
+		} else {
+			return d
+		}
+	}
+	sort.SliceStable(reps, func(i, j int) bool {
+		if di, dj := delta(reps[i]), delta(reps[j]); di != dj {
+			return di > dj
+		}
+		return reps[i].URL < reps[j].URL
+	})
+}
+
+// writeTrendCSV writes a CSV file to w containing all of the history within dir
+// for cfg's strategy. Each row contains the scores from a single run, and there is
+// a column for each combination of URL and category. Missing scores are left empty.
+func writeTrendCSV(w io.Writer, dir string, cfg *reportConfig) error {
+	suffix := "-" + strategy(cfg) + ".jsonl"
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+suffix))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	type column struct{ url, cat string }
+	var cols []column
+	seenCols := make(map[column]struct{})
+	var times []time.Time
+	scores := make(map[time.Time]map[column]int)
+
+	for _, p := range paths {
+		entries, err := readHistory(p)
+		if err != nil {
+			return err
+		}
+		for _, ent := range entries {
+			u := ent.URL
+			if u == "" {
+				u = strings.TrimSuffix(filepath.Base(p), suffix) // older entries lack URLs
+			}
+			if _, ok := scores[ent.Time]; !ok {
+				times = append(times, ent.Time)
+				scores[ent.Time] = make(map[column]int)
+			}
+			for _, id := range knownCategories {
+				score, ok := ent.Scores[id]
+				if !ok {
+					continue
+				}
+				col := column{u, id}
+				if _, ok := seenCols[col]; !ok {
+					seenCols[col] = struct{}{}
+					cols = append(cols, col)
+				}
+				scores[ent.Time][col] = score
+			}
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	cw := csv.NewWriter(w)
+	header := []string{"time"}
+	for _, col := range cols {
+		header = append(header, col.url+" "+col.cat)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, t := range times {
+		row := []string{t.Format(time.RFC3339)}
+		for _, col := range cols {
+			var val string
+			if score, ok := scores[t][col]; ok {
+				val = strconv.Itoa(score)
+			}
+			row = append(row, val)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
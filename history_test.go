@@ -0,0 +1,110 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	dir := t.TempDir()
+	cfg := reportConfig{startTime: time.Unix(1000, 0).UTC()}
+	reps := []*report{
+		{URL: "https://example.org/a", Categories: []category{{ID: "performance", Score: 80}}},
+		{URL: "https://example.org/b"}, // failed
+	}
+	if err := appendHistory(dir, reps, &cfg); err != nil {
+		t.Fatal("appendHistory failed: ", err)
+	}
+	cfg.startTime = time.Unix(2000, 0).UTC()
+	reps[0].Categories[0].Score = 90
+	if err := appendHistory(dir, reps, &cfg); err != nil {
+		t.Fatal("appendHistory failed: ", err)
+	}
+
+	got, err := readHistory(historyPath(dir, reps[0].URL, &cfg))
+	if err != nil {
+		t.Fatal("readHistory failed: ", err)
+	}
+	want := []historyEntry{
+		{Time: time.Unix(1000, 0).UTC(), URL: reps[0].URL, Scores: map[string]int{"performance": 80}},
+		{Time: time.Unix(2000, 0).UTC(), URL: reps[0].URL, Scores: map[string]int{"performance": 90}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readHistory returned %+v; want %+v", got, want)
+	}
+
+	last, err := readLastHistory(dir, reps, &cfg)
+	if err != nil {
+		t.Fatal("readLastHistory failed: ", err)
+	}
+	if want := map[string]historyEntry{reps[0].URL: want[1]}; !reflect.DeepEqual(last, want) {
+		t.Errorf("readLastHistory returned %+v; want %+v", last, want)
+	}
+}
+
+func TestWriteTrendCSV(t *testing.T) {
+	dir := t.TempDir()
+	cfg := reportConfig{}
+	for i, reps := range [][]*report{
+		{newTestReport("https://example.org/a", map[string]int{"performance": 80, "seo": 90})},
+		{newTestReport("https://example.org/a", map[string]int{"performance": 85, "seo": 90}), newTestReport("https://example.org/b", map[string]int{"performance": 50, "seo": 100})},
+		{newTestReport("https://example.org/b", map[string]int{"performance": 55, "seo": 100})},
+	} {
+		cfg.startTime = time.Unix(int64(1000*(i+1)), 0).UTC()
+		if err := appendHistory(dir, reps, &cfg); err != nil {
+			t.Fatal("appendHistory failed: ", err)
+		}
+	}
+
+	var b bytes.Buffer
+	if err := writeTrendCSV(&b, dir, &cfg); err != nil {
+		t.Fatal("writeTrendCSV failed: ", err)
+	}
+	want := strings.Join([]string{
+		"time,https://example.org/a performance,https://example.org/a seo," +
+			"https://example.org/b performance,https://example.org/b seo",
+		"1970-01-01T00:16:40Z,80,90,,",
+		"1970-01-01T00:33:20Z,85,90,50,100",
+		"1970-01-01T00:50:00Z,,,55,100",
+		"",
+	}, "\n")
+	if got := b.String(); got != want {
+		t.Errorf("writeTrendCSV wrote:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSortByDelta(t *testing.T) {
+	mkent := func(perf int) historyEntry {
+		return historyEntry{Scores: map[string]int{"performance": perf}}
+	}
+	reps := []*report{
+		newTestReport("https://example.org/new", map[string]int{"performance": 50}), // no history
+		newTestReport("https://example.org/same", map[string]int{"performance": 70}),
+		{URL: "https://example.org/failed"},
+		newTestReport("https://example.org/up", map[string]int{"performance": 90}),
+		newTestReport("https://example.org/down2", map[string]int{"performance": 60}),
+		newTestReport("https://example.org/down1", map[string]int{"performance": 60}),
+	}
+	last := map[string]historyEntry{
+		"https://example.org/same":   mkent(70),
+		"https://example.org/up":     mkent(80),
+		"https://example.org/down1":  mkent(70),
+		"https://example.org/down2":  mkent(90),
+		"https://example.org/failed": mkent(90),
+	}
+	sortByDelta(reps, last, "performance")
+	var got []string
+	for _, rep := range reps {
+		got = append(got, urlPath(rep.URL))
+	}
+	want := []string{"/down2", "/down1", "/up", "/same", "/failed", "/new"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sortByDelta produced %q; want %q", got, want)
+	}
+}
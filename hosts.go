@@ -0,0 +1,51 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"net/url"
+	"sync"
+)
+
+// hostLimiter limits the number of distinct hosts that are being checked simultaneously.
+type hostLimiter struct {
+	max    int // 0 for no limit
+	mu     sync.Mutex
+	cond   *sync.Cond
+	active map[string]int // number of in-progress checks keyed by host
+}
+
+func newHostLimiter(max int) *hostLimiter {
+	l := &hostLimiter{max: max, active: make(map[string]int)}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// acquire blocks until u's host can be checked and returns a function
+// that must be called when the check is complete.
+func (l *hostLimiter) acquire(u string) (release func()) {
+	if l.max <= 0 {
+		return func() {}
+	}
+	var host string
+	if pu, err := url.Parse(u); err == nil {
+		host = pu.Hostname()
+	}
+
+	l.mu.Lock()
+	for l.active[host] == 0 && len(l.active) >= l.max {
+		l.cond.Wait()
+	}
+	l.active[host]++
+	l.mu.Unlock()
+
+	return func() {
+		l.mu.Lock()
+		if l.active[host]--; l.active[host] == 0 {
+			delete(l.active, host)
+			l.cond.Broadcast()
+		}
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHostLimiter(t *testing.T) {
+	l := newHostLimiter(1)
+	releaseA1 := l.acquire("https://a.example.org/1")
+	releaseA2 := l.acquire("https://a.example.org/2") // same host doesn't block
+
+	acquired := make(chan struct{})
+	go func() {
+		release := l.acquire("https://b.example.org/")
+		close(acquired)
+		release()
+	}()
+
+	releaseA1()
+	select {
+	case <-acquired:
+		t.Fatal("Second host acquired while first host still active")
+	case <-time.After(10 * time.Millisecond):
+	}
+	releaseA2()
+	select {
+	case <-acquired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Second host not acquired after first host released")
+	}
+}
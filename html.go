@@ -0,0 +1,290 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	htemplate "html/template"
+	"io"
+)
+
+// htmlColumn is a cell in the HTML summary table.
+type htmlColumn struct{ Text, Title, Href, Class string }
+
+// htmlReport is a per-URL section in an HTML document.
+type htmlReport struct {
+	ID         string // element ID for links from the table of contents
+	URL        string
+	Error      string // set if the report couldn't be fetched
+	Categories []htmlCategory
+}
+
+// htmlTOCEntry is a link to an htmlReport in the table of contents.
+type htmlTOCEntry struct{ ID, URL, Scores string }
+
+// htmlCategory is a collapsible category within an htmlReport.
+type htmlCategory struct {
+	Title, Score, Class string
+	Audits              []htmlAudit
+}
+
+// htmlAudit is a single audit within an htmlCategory.
+type htmlAudit struct {
+	Score, Class, Title, Value, Savings string
+	Tables                              []htmlTable
+}
+
+// htmlTable is a table of audit details.
+type htmlTable struct {
+	Rows [][]string // first row is the header
+	More string     // note describing omitted rows, if any
+}
+
+// Cols returns the number of columns in t's header.
+func (t htmlTable) Cols() int {
+	if len(t.Rows) == 0 {
+		return 1
+	}
+	return len(t.Rows[0])
+}
+
+// writeHTML writes reps to w as a standalone HTML document containing
+// a summary table followed by each report's categories and audits.
+func writeHTML(w io.Writer, reps []*report, cfg *reportConfig) error {
+	html, err := generateHTML(reps, cfg, formatStartTime(cfg), true)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, html)
+	return err
+}
+
+// generateHTML generates an HTML document summarizing reps. startTime describes when
+// the reports were generated; if it is empty, the footer with the time, label, and
+// command is omitted. Per-URL sections are included if details is true.
+func generateHTML(reps []*report, cfg *reportConfig, startTime string, details bool) (string, error) {
+	data := struct {
+		Rows    [][]htmlColumn
+		TOC     []htmlTOCEntry
+		Reports []htmlReport
+		Title   string
+		Time    string
+		Label   string
+		Command string
+		Style   htemplate.CSS
+	}{
+		Rows:    [][]htmlColumn{{{Text: "URL", Title: "URL"}}}, // first row is header
+		Title:   cfg.title,
+		Time:    startTime,
+		Label:   cfg.label,
+		Command: cfg.command,
+		Style:   htemplate.CSS(themeCSS(cfg.theme)),
+	}
+	scoreClass := func(score int) string {
+		if band := scoreBand(score); band != "" && cfg.theme != themeNone {
+			return "score-" + band
+		}
+		return ""
+	}
+
+	cats := summaryCategories(reps)
+	for _, cat := range cats {
+		data.Rows[0] = append(data.Rows[0], htmlColumn{
+			Text:  cat.Abbrev,
+			Title: cat.Title,
+		})
+	}
+	for _, rep := range reps {
+		row := []htmlColumn{htmlColumn{Text: displayURL(rep.URL, cfg), Href: stripUserinfo(rep.URL)}}
+		for _, cat := range cats {
+			var col htmlColumn
+			if c := findCategory(rep, cat.ID); c != nil {
+				col.Text = formatScore(c.Score, c.ScoreFloat, cfg)
+				col.Class = scoreClass(c.Score)
+			}
+			row = append(row, col)
+		}
+		data.Rows = append(data.Rows, row)
+	}
+
+	if details {
+		for i, rep := range reps {
+			if skipReport(rep, cfg) {
+				continue
+			}
+			hr := htmlReport{ID: fmt.Sprintf("report-%d", i+1), URL: stripUserinfo(rep.URL), Error: rep.Error}
+			if cfg.toc {
+				data.TOC = append(data.TOC, htmlTOCEntry{ID: hr.ID, URL: hr.URL, Scores: tocScores(rep, cfg)})
+			}
+			if len(rep.Categories) == 0 && hr.Error == "" {
+				hr.Error = "unknown error"
+			}
+			for _, cat := range rep.Categories {
+				if !showCategory(&cat, cfg) {
+					continue
+				}
+				hc := htmlCategory{
+					Title: cat.Title,
+					Score: formatScore(cat.Score, cat.ScoreFloat, cfg),
+					Class: scoreClass(cat.Score),
+				}
+				for _, aud := range cat.Audits {
+					if !showAudit(&aud, cfg) {
+						continue
+					}
+					ha := htmlAudit{
+						Score:   ".",
+						Class:   scoreClass(aud.Score),
+						Title:   aud.Title,
+						Value:   aud.Value,
+						Savings: formatSavings(&aud),
+					}
+					if aud.Score >= 0 {
+						ha.Score = formatScore(aud.Score, aud.ScoreFloat, cfg)
+					}
+					if cfg.maxDetails != 0 {
+						for _, table := range aud.Details {
+							ha.Tables = append(ha.Tables, limitDetails(table, cfg))
+						}
+					}
+					hc.Audits = append(hc.Audits, ha)
+				}
+				hr.Categories = append(hr.Categories, hc)
+			}
+			data.Reports = append(data.Reports, hr)
+		}
+	}
+
+	return runTemplate(htemplate.New(""), htmlTemplate, &data)
+}
+
+// limitDetails returns a copy of table, a table from audit.Details, limited to
+// cfg.maxDetailCols columns and to cfg.maxDetails rows (including its header).
+// As in formatDetails, the last row is replaced by a cfg.moreFormat note if rows
+// are omitted.
+func limitDetails(table [][]string, cfg *reportConfig) htmlTable {
+	var ht htmlTable
+	if cfg.maxDetails > 0 && len(table) > cfg.maxDetails {
+		ht.More = fmt.Sprintf(cfg.moreFormat, len(table)-cfg.maxDetails+1)
+		table = table[:cfg.maxDetails-1]
+	}
+	ht.Rows = make([][]string, len(table))
+	for i, row := range table {
+		if cfg.maxDetailCols > 0 && len(row) > cfg.maxDetailCols {
+			row = row[:cfg.maxDetailCols]
+		}
+		ht.Rows[i] = row
+	}
+	return ht
+}
+
+// Colors used by the HTML themes.
+type themeColors struct{ bg, text, pass, average, fail string }
+
+var (
+	lightColors = themeColors{"#ffffff", "#202124", "#018642", "#d04900", "#eb0f00"}
+	darkColors  = themeColors{"#202124", "#e8eaed", "#0cce6b", "#ffa400", "#ff4e42"}
+)
+
+// themeCSS returns the CSS rules to include in the HTML message for the named theme.
+func themeCSS(theme string) string {
+	rules := func(c themeColors) string {
+		return fmt.Sprintf("body{background-color:%s;color:%s}"+
+			"a{color:inherit;text-decoration:none}"+
+			".score-pass{color:%s}.score-average{color:%s}.score-fail{color:%s}",
+			c.bg, c.text, c.pass, c.average, c.fail)
+	}
+	switch theme {
+	case themeLight:
+		return rules(lightColors)
+	case themeDark:
+		return rules(darkColors)
+	case themeAuto:
+		return rules(lightColors) + "@media (prefers-color-scheme:dark){" + rules(darkColors) + "}"
+	}
+	return ""
+}
+
+const htmlTemplate = `
+<!DOCTYPE html>
+<html lang="en">
+  <head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, minimum-scale=1">
+    <title>{{if .Title}}{{.Title}}{{else}}check-page-speed{{end}}</title>
+    {{- if .Style}}
+    <style>{{.Style}}</style>
+    {{- end}}
+  </head>
+  <body>
+    {{- if .Title}}
+    <p><b>{{.Title}}</b></p>
+    {{- end}}
+    <table>
+      {{- range $i, $row := .Rows}}
+      <tr>
+        {{- range $j, $col := $row}}
+        {{if eq $i 0}}<th{{else}}<td{{end}}
+            {{- if eq $j 0}} align="left"
+            {{- else}} align="right" style="padding-left:8px"
+            {{- end}}{{if $col.Title}} title="{{$col.Title}}"{{end}}
+            {{- if $col.Class}} class="{{$col.Class}}"{{end}}>
+          {{- if $col.Href}}<a href="{{$col.Href}}"
+            {{- if not $.Style}} style="text-decoration:none;color:black"{{end}}>{{end -}}
+            {{$col.Text}}
+          {{- if $col.Href}}</a>{{end -}}
+        {{if eq $i 0}}</th>{{else}}</td>{{end}}
+        {{- end}}
+      </tr>
+      {{- end}}
+    </table>
+    {{- if .TOC}}
+    <ol>
+      {{- range .TOC}}
+      <li><a href="#{{.ID}}">{{.URL}}</a> {{.Scores}}</li>
+      {{- end}}
+    </ol>
+    {{- end}}
+    {{- range .Reports}}
+    <h2 id="{{.ID}}"><a href="{{.URL}}">{{.URL}}</a></h2>
+    {{- if .Error}}
+    <p>Report could not be fetched: {{.Error}}</p>
+    {{- end}}
+    {{- range .Categories}}
+    <details>
+      <summary><span{{with .Class}} class="{{.}}"{{end}}>{{.Score}}</span> {{.Title}}</summary>
+      {{- if .Audits}}
+      <ul>
+        {{- range .Audits}}
+        <li><span{{with .Class}} class="{{.}}"{{end}}>{{.Score}}</span> {{.Title}}
+          {{- if .Value}}: {{.Value}}{{end}}{{if .Savings}} (est. {{.Savings}}){{end}}
+          {{- range .Tables}}
+          <table>
+            {{- range $i, $row := .Rows}}
+            <tr>{{range $row}}{{if eq $i 0}}<th align="left">{{.}}</th>{{else}}<td>{{.}}</td>{{end}}{{end}}</tr>
+            {{- end}}
+            {{- if .More}}
+            <tr><td colspan="{{.Cols}}">{{.More}}</td></tr>
+            {{- end}}
+          </table>
+          {{- end}}
+        </li>
+        {{- end}}
+      </ul>
+      {{- end}}
+    </details>
+    {{- end}}
+    {{- end}}
+    {{- if .Time}}
+    <p>Generated by <a href="https://github.com/derat/check-page-speed">check-page-speed</a> at {{.Time}}.</p>
+    {{- if .Label}}
+    <p>Label: {{.Label}}</p>
+    {{- end}}
+    {{- if .Command}}
+    <p>Command: <code>{{.Command}}</code></p>
+    {{- end}}
+    {{- end}}
+  </body>
+</html>
+`
@@ -12,8 +12,8 @@ import (
 	"io"
 	"net/url"
 	"os"
+	"os/exec"
 	"os/user"
-	"strconv"
 	"strings"
 	ttemplate "text/template"
 	"time"
@@ -22,14 +22,28 @@ import (
 )
 
 const (
-	// SMTP connection info.
-	mailHost = "localhost"
-	mailPort = 25
+	// Default SMTP connection info.
+	defaultSMTPHost = "localhost"
+	defaultSMTPPort = 25
+
+	smtpPassEnv = "PAGE_SPEED_SMTP_PASS" // environment variable containing SMTP password
+)
+
+// Values for -mail-transport.
+const (
+	mailSMTP     = "smtp"
+	mailSendmail = "sendmail"
 )
 
+const defaultSendmailPath = "/usr/sbin/sendmail"
+
 // sendMail sends email to cfg.mailAddr with a summary of the supplied reports
 // in the message body and a text attachment with the full reports.
+// The attachment is omitted if cfg.noAttachment or cfg.summaryOnly is set.
+// If cfg.summaryOnly is set, the body also omits the footer describing the run.
+// If cfg.emlOut is set, the message is written to that path instead.
 func sendMail(reports []*report, cfg *reportConfig) error {
+	cfg = withoutColor(cfg)
 	text, html, err := generateBody(reports, cfg)
 	if err != nil {
 		return err
@@ -39,29 +53,27 @@ func sendMail(reports []*report, cfg *reportConfig) error {
 		return fmt.Errorf("couldn't get from address (consider setting $EMAIL): %v", err)
 	}
 
-	// Try to construct a subject like "example.com mobile page speed for Dec 7".
-	subject := "Page speed report"
-	if u, err := url.Parse(reports[0].URL); err == nil {
-		subject = strings.TrimPrefix(u.Hostname(), "www.")
-		if cfg.mobile {
-			subject += " mobile"
-		} else {
-			subject += " desktop"
-		}
-		subject += " page speed"
-	}
-	subject += " for " + cfg.startTime.Format("Jan 2")
-
 	msg := gomail.NewMessage()
 	msg.SetHeader("From", from)
-	msg.SetHeader("To", cfg.mailAddr)
-	msg.SetHeader("Subject", subject)
+	if cfg.mailAddr != "" {
+		msg.SetHeader("To", cfg.mailAddr)
+	}
+	msg.SetHeader("Subject", mailSubject(reports, cfg))
 	msg.SetBody("text/plain", text)
 	msg.AddAlternative("text/html", html)
-	msg.Attach(fmt.Sprintf("page-speed-%s.txt", cfg.startTime.Format("20060102-030405")),
-		gomail.SetCopyFunc(func(w io.Writer) error { return writeReports(w, reports, cfg) }),
-		gomail.SetHeader(map[string][]string{"Content-Type": []string{"text/plain"}}),
-	)
+	if !cfg.noAttachment && !cfg.summaryOnly {
+		msg.Attach(fmt.Sprintf("page-speed-%s.txt", cfg.startTime.Format("20060102-030405")),
+			gomail.SetCopyFunc(func(w io.Writer) error { return writeReports(w, reports, cfg) }),
+			gomail.SetHeader(map[string][]string{"Content-Type": []string{"text/plain"}}),
+		)
+	}
+
+	if cfg.emlOut != "" {
+		return writeFile(cfg.emlOut, func(w io.Writer) error {
+			_, err := msg.WriteTo(w)
+			return err
+		})
+	}
 
 	// Make it easier to test generated messages during development.
 	if cfg.mailAddr == "-" {
@@ -69,7 +81,11 @@ func sendMail(reports []*report, cfg *reportConfig) error {
 		return err
 	}
 
-	dialer := gomail.Dialer{Host: mailHost, Port: mailPort}
+	if cfg.mailTransport == mailSendmail {
+		return sendmail(cfg.sendmailPath, msg)
+	}
+
+	dialer := gomail.NewDialer(cfg.smtpHost, cfg.smtpPort, cfg.smtpUser, cfg.smtpPass)
 	if dialer.Host == "localhost" {
 		// Try to work around "x509: certificate is not valid for any names, but wanted to match
 		// localhost" errors, since we're just connecting to localhost anyway:
@@ -79,6 +95,54 @@ func sendMail(reports []*report, cfg *reportConfig) error {
 	return dialer.DialAndSend(msg)
 }
 
+// sendmail sends msg by piping it to the sendmail binary at path.
+// Recipients are read from the message's headers.
+func sendmail(path string, msg *gomail.Message) error {
+	cmd := exec.Command(path, "-t", "-i")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	_, werr := msg.WriteTo(stdin)
+	if err := stdin.Close(); werr == nil {
+		werr = err
+	}
+	if err := cmd.Wait(); err != nil {
+		if s := strings.TrimSpace(stderr.String()); s != "" {
+			return fmt.Errorf("%v: %v (%s)", path, err, s)
+		}
+		return fmt.Errorf("%v: %v", path, err)
+	}
+	return werr
+}
+
+// mailSubject returns the subject to use for a message describing reports.
+func mailSubject(reports []*report, cfg *reportConfig) string {
+	// Try to construct a subject like "example.com mobile page speed for Dec 7".
+	subject := "Page speed report"
+	if cfg.title != "" {
+		subject = cfg.title
+	} else if u, err := url.Parse(reports[0].URL); err == nil {
+		subject = strings.TrimPrefix(u.Hostname(), "www.")
+		if cfg.mobile {
+			subject += " mobile"
+		} else {
+			subject += " desktop"
+		}
+		subject += " page speed"
+	}
+	subject += " for " + cfg.startTime.Format("Jan 2")
+	if cfg.label != "" {
+		subject += " (" + cfg.label + ")"
+	}
+	return subject
+}
+
 // getMailFrom tries to find an email address to use in the "From" header.
 func getMailFrom() (string, error) {
 	for _, name := range []string{
@@ -104,50 +168,38 @@ func getMailFrom() (string, error) {
 	return fmt.Sprintf("%v@%v", user.Username, host), nil
 }
 
+// formatStartTime returns a string describing cfg.startTime for inclusion in output.
+// If cfg.showDuration is set, the run's duration is also included. (Output is generated
+// at the end of the run, so a time relative to now wouldn't mean anything to readers.)
+func formatStartTime(cfg *reportConfig) string {
+	// "Mon, 02 Jan 2006 15:04:05 -0700"
+	s := cfg.startTime.Format(time.RFC1123Z)
+	if cfg.showDuration {
+		s += " (took " + humanizeDuration(time.Since(cfg.startTime)) + ")"
+	}
+	return s
+}
+
 // generateBody generates text and HTML email message bodies.
 func generateBody(reports []*report, cfg *reportConfig) (text, html string, err error) {
-	// "Mon, 02 Jan 2006 15:04:05 -0700"
-	startTime := cfg.startTime.Format(time.RFC1123Z)
+	var startTime string
+	if !cfg.summaryOnly {
+		startTime = formatStartTime(cfg)
+	}
 
 	// Generate the text version.
 	var sum bytes.Buffer
 	if err := writeSummary(&sum, reports, cfg); err != nil {
 		return "", "", err
 	}
-	tdata := &struct{ Summary, Time string }{strings.TrimSpace(sum.String()), startTime}
+	tdata := &struct{ Summary, Time, Label, Command string }{
+		strings.TrimSpace(sum.String()), startTime, cfg.label, cfg.command}
 	if text, err = runTemplate(ttemplate.New(""), textTemplate, tdata); err != nil {
 		return "", "", err
 	}
 
 	// Generate the HTML version.
-	type column struct{ Text, Title, Href string }
-	hdata := struct {
-		Rows [][]column
-		Time string
-	}{
-		Rows: [][]column{{{Text: "URL", Title: "URL"}}}, // first row is header
-		Time: startTime,
-	}
-	for _, rep := range reports {
-		// Add the categories from the first non-failed report to the heading row.
-		if len(hdata.Rows[0]) == 1 && len(rep.Categories) > 0 {
-			for _, cat := range rep.Categories {
-				hdata.Rows[0] = append(hdata.Rows[0], column{
-					Text:  cat.Abbrev,
-					Title: cat.Title,
-				})
-			}
-		}
-		row := []column{column{Text: rep.URL, Href: rep.URL}}
-		if !cfg.fullURLs {
-			row[0].Text = urlPath(rep.URL)
-		}
-		for _, cat := range rep.Categories {
-			row = append(row, column{Text: strconv.Itoa(cat.Score)})
-		}
-		hdata.Rows = append(hdata.Rows, row)
-	}
-	if html, err = runTemplate(htemplate.New(""), htmlTemplate, &hdata); err != nil {
+	if html, err = generateHTML(reports, cfg, startTime, false); err != nil {
 		return "", "", err
 	}
 
@@ -180,37 +232,17 @@ func runTemplate(tmpl interface{}, text string, data interface{}) (string, error
 
 const textTemplate = `
 {{.Summary}}
+{{- if .Time}}
 
 Generated by https://github.com/derat/check-page-speed at
 {{.Time}}.
-`
+{{- if .Label}}
+
+Label: {{.Label}}
+{{- end}}
+{{- if .Command}}
 
-const htmlTemplate = `
-<!DOCTYPE html>
-<html lang="en">
-  <head>
-    <meta charset="utf-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1, minimum-scale=1">
-    <title>check-page-speed</title>
-  </head>
-  <body>
-    <table>
-      {{- range $i, $row := .Rows}}
-      <tr>
-        {{- range $j, $col := $row}}
-        {{if eq $i 0}}<th{{else}}<td{{end}}
-            {{- if eq $j 0}} align="left"
-            {{- else}} align="right" style="padding-left:8px"
-            {{- end}}{{if $col.Title}} title="{{$col.Title}}"{{end}}>
-          {{- if $col.Href}}<a href="{{$col.Href}}" style="text-decoration:none;color:black">{{end -}}
-            {{$col.Text}}
-          {{- if $col.Href}}</a>{{end -}}
-        {{if eq $i 0}}</th>{{else}}</td>{{end}}
-        {{- end}}
-      </tr>
-      {{- end}}
-    </table>
-    <p>Generated by <a href="https://github.com/derat/check-page-speed">check-page-speed</a> at {{.Time}}.</p>
-  </body>
-</html>
+Command: {{.Command}}
+{{- end}}
+{{- end}}
 `
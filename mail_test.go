@@ -0,0 +1,167 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/gomail.v2"
+)
+
+func TestFormatStartTime(t *testing.T) {
+	start := time.Now().Add(-150 * time.Second)
+	cfg := reportConfig{startTime: start}
+	if got, want := formatStartTime(&cfg), start.Format(time.RFC1123Z); got != want {
+		t.Errorf("formatStartTime() = %q; want %q", got, want)
+	}
+	cfg.showDuration = true
+	if got, want := formatStartTime(&cfg), start.Format(time.RFC1123Z)+" (took 2 minutes)"; got != want {
+		t.Errorf("formatStartTime() with showDuration = %q; want %q", got, want)
+	}
+}
+
+func TestGenerateBody_Label(t *testing.T) {
+	reps := []*report{{URL: "https://example.org/", Categories: []category{
+		{ID: "performance", Abbrev: "Perf", Score: 90},
+	}}}
+	cfg := reportConfig{startTime: time.Date(2022, 12, 7, 10, 0, 0, 0, time.UTC), label: "pre-deploy"}
+	text, html, err := generateBody(reps, &cfg)
+	if err != nil {
+		t.Fatal("generateBody failed: ", err)
+	}
+	if want := "\n\nLabel: pre-deploy\n"; !strings.Contains(text, want) {
+		t.Errorf("Text body doesn't contain %q:\n%s", want, text)
+	}
+	if want := "<p>Label: pre-deploy</p>"; !strings.Contains(html, want) {
+		t.Errorf("HTML body doesn't contain %q:\n%s", want, html)
+	}
+}
+
+func TestGenerateBody_SummaryOnly(t *testing.T) {
+	reps := []*report{{URL: "https://example.org/", Categories: []category{
+		{ID: "performance", Abbrev: "Perf", Score: 90},
+	}}}
+	cfg := reportConfig{
+		startTime: time.Date(2022, 12, 7, 10, 0, 0, 0, time.UTC),
+		label:     "pre-deploy",
+		command:   "check-page-speed https://example.org/",
+	}
+	for _, summaryOnly := range []bool{false, true} {
+		cfg.summaryOnly = summaryOnly
+		text, html, err := generateBody(reps, &cfg)
+		if err != nil {
+			t.Fatal("generateBody failed: ", err)
+		}
+		if want := "Perf"; !strings.Contains(text, want) {
+			t.Errorf("Text body with summaryOnly=%v doesn't contain %q:\n%s", summaryOnly, want, text)
+		}
+		for _, s := range []string{"Generated by", "pre-deploy", cfg.command} {
+			if got := strings.Contains(text, s); got == summaryOnly {
+				t.Errorf("Text body with summaryOnly=%v contains %q = %v:\n%s", summaryOnly, s, got, text)
+			}
+			if got := strings.Contains(html, s); got == summaryOnly {
+				t.Errorf("HTML body with summaryOnly=%v contains %q = %v:\n%s", summaryOnly, s, got, html)
+			}
+		}
+	}
+}
+
+func TestMailSubject(t *testing.T) {
+	reps := []*report{{URL: "https://www.example.org/"}}
+	date := time.Date(2022, 12, 7, 10, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		cfg  reportConfig
+		want string
+	}{
+		{reportConfig{}, "example.org desktop page speed for Dec 7"},
+		{reportConfig{mobile: true, label: "pre-deploy"}, "example.org mobile page speed for Dec 7 (pre-deploy)"},
+		{reportConfig{title: "Nightly page speed — staging"}, "Nightly page speed — staging for Dec 7"},
+	} {
+		tc.cfg.startTime = date
+		if got := mailSubject(reps, &tc.cfg); got != tc.want {
+			t.Errorf("mailSubject(..., %+v) = %q; want %q", tc.cfg, got, tc.want)
+		}
+	}
+}
+
+func TestGenerateBody_Title(t *testing.T) {
+	reps := []*report{{URL: "https://example.org/", Categories: []category{
+		{ID: "performance", Abbrev: "Perf", Score: 90},
+	}}}
+	cfg := reportConfig{startTime: time.Date(2022, 12, 7, 10, 0, 0, 0, time.UTC), title: "Nightly"}
+	text, html, err := generateBody(reps, &cfg)
+	if err != nil {
+		t.Fatal("generateBody failed: ", err)
+	}
+	if want := "Nightly\n\nURL"; !strings.HasPrefix(text, want) {
+		t.Errorf("Text body doesn't start with %q:\n%s", want, text)
+	}
+	for _, want := range []string{"<title>Nightly</title>", "<p><b>Nightly</b></p>"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML body doesn't contain %q:\n%s", want, html)
+		}
+	}
+}
+
+func TestGenerateBody_Escaping(t *testing.T) {
+	// The HTML body doesn't include audit details, but page-controlled strings
+	// like URLs and titles should still be escaped by html/template.
+	const evil = `<script>alert("x")</script>`
+	reps := []*report{{URL: "https://example.org/" + evil, Categories: []category{
+		{ID: "performance", Title: "Perf " + evil, Abbrev: "Perf", Score: 90},
+	}}}
+	cfg := reportConfig{startTime: time.Date(2022, 12, 7, 10, 0, 0, 0, time.UTC), fullURLs: true, title: evil}
+	_, html, err := generateBody(reps, &cfg)
+	if err != nil {
+		t.Fatal("generateBody failed: ", err)
+	}
+	if strings.Contains(html, "<script>") {
+		t.Errorf("HTML body contains unescaped markup:\n%s", html)
+	}
+	if want := "&lt;script&gt;"; !strings.Contains(html, want) {
+		t.Errorf("HTML body doesn't contain %q:\n%s", want, html)
+	}
+}
+
+func TestSendmail(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	bin := filepath.Join(dir, "sendmail")
+	script := "#!/bin/sh\necho \"$@\" >" + out + "\ncat >>" + out + "\n"
+	if err := os.WriteFile(bin, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := gomail.NewMessage()
+	msg.SetHeader("To", "me@example.org")
+	msg.SetHeader("Subject", "Test")
+	msg.SetBody("text/plain", "Hello")
+	if err := sendmail(bin, msg); err != nil {
+		t.Fatal("sendmail failed: ", err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(b)
+	for _, want := range []string{"-t -i\n", "To: me@example.org\r\n", "Subject: Test\r\n", "Hello"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("sendmail received %q; want %q", got, want)
+		}
+	}
+
+	// Errors from the binary should be reported.
+	if err := os.WriteFile(bin, []byte("#!/bin/sh\necho 'no recipients' >&2\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := sendmail(bin, msg); err == nil {
+		t.Error("sendmail unexpectedly succeeded")
+	} else if !strings.Contains(err.Error(), "no recipients") {
+		t.Errorf("sendmail error %q doesn't contain stderr", err)
+	}
+}
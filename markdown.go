@@ -0,0 +1,129 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// markdownEscaper escapes characters that have special meaning in GitHub Flavored Markdown.
+// Since Markdown passes inline HTML through, HTML's special characters are also escaped.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"_", `\_`,
+	"`", "\\`",
+	"[", `\[`,
+	"]", `\]`,
+	"|", `\|`,
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+)
+
+// escapeMarkdown escapes s for use in Markdown text (including table cells).
+func escapeMarkdown(s string) string { return markdownEscaper.Replace(s) }
+
+// markdownURLEscaper escapes characters that would end a link destination
+// or a table cell.
+var markdownURLEscaper = strings.NewReplacer(
+	" ", "%20",
+	"(", "%28",
+	")", "%29",
+	"|", "%7C",
+)
+
+// markdownLink returns a Markdown link to u using text as the link text.
+func markdownLink(text, u string) string {
+	return "[" + escapeMarkdown(text) + "](" + markdownURLEscaper.Replace(u) + ")"
+}
+
+// writeMarkdown writes reps to w as a GitHub Flavored Markdown document containing
+// a summary table followed by each report's category scores, audits, and details.
+func writeMarkdown(w io.Writer, reps []*report, cfg *reportConfig) error {
+	if cfg.title != "" {
+		fmt.Fprintf(w, "# %s\n\n", escapeMarkdown(cfg.title))
+	}
+
+	cats := summaryCategories(reps)
+	head := []string{"URL"}
+	sep := []string{"---"}
+	for _, cat := range cats {
+		head = append(head, escapeMarkdown(cat.Abbrev))
+		sep = append(sep, "---:")
+	}
+	writeMarkdownRow(w, head)
+	writeMarkdownRow(w, sep)
+	for _, rep := range reps {
+		u := stripUserinfo(rep.URL)
+		row := []string{markdownLink(displayURL(rep.URL, cfg), u)}
+		for _, cat := range cats {
+			var val string
+			if c := findCategory(rep, cat.ID); c != nil {
+				val = formatScore(c.Score, c.ScoreFloat, cfg)
+			}
+			row = append(row, val)
+		}
+		writeMarkdownRow(w, row)
+	}
+
+	for _, rep := range reps {
+		u := stripUserinfo(rep.URL)
+		fmt.Fprintf(w, "\n# %s\n\n", markdownLink(u, u))
+		if len(rep.Categories) == 0 {
+			fmt.Fprintln(w, "Report could not be fetched.")
+			continue
+		}
+		for i, cat := range rep.Categories {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "## %s: %s\n\n", escapeMarkdown(cat.Title), formatScore(cat.Score, cat.ScoreFloat, cfg))
+			var n int
+			if cfg.audits != auditsNone {
+				for _, aud := range cat.Audits {
+					if !showAudit(&aud, cfg) {
+						continue
+					}
+					score := "."
+					if aud.Score >= 0 {
+						score = formatScore(aud.Score, aud.ScoreFloat, cfg)
+					}
+					ln := "**" + score + "** " + escapeMarkdown(aud.Title)
+					if aud.Value != "" {
+						ln += ": " + escapeMarkdown(aud.Value)
+					}
+					if s := formatSavings(&aud); s != "" {
+						ln += " (est. " + s + ")"
+					}
+					fmt.Fprintf(w, "- %s\n", ln)
+					n++
+
+					// Detail tables are written as preformatted text within the list item.
+					if cfg.maxDetails != 0 {
+						for _, table := range aud.Details {
+							fmt.Fprintln(w)
+							fmt.Fprintln(w, "  ```")
+							for _, ln := range formatDetails(table, cfg) {
+								fmt.Fprintf(w, "  %s\n", strings.TrimRight(ln, " "))
+							}
+							fmt.Fprintln(w, "  ```")
+						}
+					}
+				}
+			}
+			if n == 0 {
+				fmt.Fprintln(w, "No audits listed.")
+			}
+		}
+	}
+	return nil
+}
+
+// writeMarkdownRow writes row to w as a line in a Markdown table.
+func writeMarkdownRow(w io.Writer, row []string) {
+	fmt.Fprintf(w, "| %s |\n", strings.Join(row, " | "))
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEscapeMarkdown(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"Plain text", "Plain text"},
+		{"Avoid `document.write()`", "Avoid \\`document.write()\\`"},
+		{"/foo_bar/*|x", `/foo\_bar/\*\|x`},
+		{`[a\b]`, `\[a\\b\]`},
+		{`Has a <meta name="viewport"> tag`, `Has a &lt;meta name="viewport"&gt; tag`},
+		{"AT&T &lt;", "AT&amp;T &amp;lt;"},
+	} {
+		if got := escapeMarkdown(tc.in); got != tc.want {
+			t.Errorf("escapeMarkdown(%q) = %q; want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	reps := []*report{
+		{URL: "https://example.org/a_b", Categories: []category{
+			{ID: "performance", Title: "Performance", Abbrev: "Perf", Score: 85, ScoreFloat: -1, Audits: []audit{
+				{ID: "lcp", Title: "Largest Contentful Paint", Score: 45, ScoreFloat: -1, Value: "3.1 s"},
+				{ID: "fcp", Title: "First Contentful Paint", Score: 100, ScoreFloat: -1},
+				{ID: "render-blocking-resources", Title: "Eliminate render-blocking resources",
+					Score: 30, ScoreFloat: -1, SavingsMs: 1200, Details: [][][]string{{
+						{"URL", "Size"},
+						{"/a.css", "10 KiB"},
+					}}},
+			}},
+			{ID: "seo", Title: "SEO", Abbrev: "SEO", Score: 100, ScoreFloat: -1},
+		}},
+		{URL: "https://example.org/c"}, // failed
+	}
+	var b bytes.Buffer
+	if err := writeMarkdown(&b, reps, &reportConfig{audits: auditsFailed, maxDetails: -1}); err != nil {
+		t.Fatal("writeMarkdown failed: ", err)
+	}
+	want := strings.Join([]string{
+		"| URL | Perf | SEO |",
+		"| --- | ---: | ---: |",
+		`| [/a\_b](https://example.org/a_b) | 85 | 100 |`,
+		"| [/c](https://example.org/c) |  |  |",
+		"",
+		`# [https://example.org/a\_b](https://example.org/a_b)`,
+		"",
+		"## Performance: 85",
+		"",
+		"- **45** Largest Contentful Paint: 3.1 s",
+		"- **30** Eliminate render-blocking resources (est. 1200 ms)",
+		"",
+		"  ```",
+		"  URL     Size",
+		"  /a.css  10 KiB",
+		"  ```",
+		"",
+		"## SEO: 100",
+		"",
+		"No audits listed.",
+		"",
+		"# [https://example.org/c](https://example.org/c)",
+		"",
+		"Report could not be fetched.",
+		"",
+	}, "\n")
+	if got := b.String(); got != want {
+		t.Errorf("writeMarkdown wrote:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	pso "google.golang.org/api/pagespeedonline/v5"
+)
+
+// labMetric describes a lab metric measured by Lighthouse.
+type labMetric struct {
+	name    string // short name used in flags and output, e.g. "lcp"
+	auditID string // ID of the audit containing the metric's numericValue
+	ratio   bool   // unitless (as opposed to a duration in milliseconds)
+}
+
+// labMetrics lists the metrics that are extracted from reports.
+var labMetrics = []labMetric{
+	{"fcp", "first-contentful-paint", false},
+	{"lcp", "largest-contentful-paint", false},
+	{"tbt", "total-blocking-time", false},
+	{"cls", "cumulative-layout-shift", true},
+	{"si", "speed-index", false},
+	{"tti", "interactive", false},
+}
+
+// findLabMetric returns the metric with the supplied short name, or nil if it isn't known.
+func findLabMetric(name string) *labMetric {
+	for i := range labMetrics {
+		if labMetrics[i].name == name {
+			return &labMetrics[i]
+		}
+	}
+	return nil
+}
+
+// getMetrics returns the lab metrics from lhr, keyed by short name.
+// Metrics that are missing from the result are omitted.
+func getMetrics(lhr *pso.LighthouseResultV5) map[string]float64 {
+	metrics := make(map[string]float64)
+	for _, m := range labMetrics {
+		if aud, ok := lhr.Audits[m.auditID]; ok && aud.NumericUnit != "" {
+			metrics[m.name] = aud.NumericValue
+		}
+	}
+	return metrics
+}
+
+// formatMetric formats v, a value of the named metric, e.g. "2500 ms" or "0.100".
+func formatMetric(name string, v float64) string {
+	if m := findLabMetric(name); m != nil && m.ratio {
+		return formatRatio(v)
+	}
+	return strconv.FormatFloat(v, 'f', 0, 64) + " ms"
+}
+
+// cwvMetricMaxes is a -metric-max value used by -cwv-pass. It mirrors the official
+// Core Web Vitals "good" thresholds (https://web.dev/vitals/). INP can't be measured
+// in the lab, so Lighthouse's recommended proxy, TBT, is used in its place.
+const cwvMetricMaxes = "lcp=2500ms,cls=0.1,tbt=200ms"
+
+// parseMetricMaxes parses a -metric-max flag value consisting of comma-separated
+// metric names and maximum values, e.g. "lcp=2500ms,tbt=0.3s,cls=0.1".
+// Durations without units are interpreted as milliseconds.
+// Later values for a metric override earlier ones.
+// The returned map is keyed by metric name.
+func parseMetricMaxes(s string) (map[string]float64, error) {
+	maxes := make(map[string]float64)
+	if s == "" {
+		return maxes, nil
+	}
+	for _, item := range strings.Split(s, ",") {
+		i := strings.IndexByte(item, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("%q isn't name=value", item)
+		}
+		name, val := strings.TrimSpace(item[:i]), strings.TrimSpace(item[i+1:])
+		m := findLabMetric(name)
+		if m == nil {
+			return nil, fmt.Errorf("unknown metric %q", name)
+		}
+		mult := 1.0
+		if !m.ratio {
+			if strings.HasSuffix(val, "ms") {
+				val = val[:len(val)-2]
+			} else if strings.HasSuffix(val, "s") {
+				val = val[:len(val)-1]
+				mult = 1000
+			}
+		}
+		v, err := strconv.ParseFloat(val, 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("bad value %q for %v", item[i+1:], name)
+		}
+		maxes[name] = v * mult
+	}
+	return maxes, nil
+}
+
+// metricBreach describes a lab metric that exceeded its maximum value.
+type metricBreach struct {
+	URL      string
+	Metric   string
+	Value    float64
+	MaxValue float64
+}
+
+func (b *metricBreach) String() string {
+	return fmt.Sprintf("%v: %v is %v (max %v)", b.URL, b.Metric,
+		formatMetric(b.Metric, b.Value), formatMetric(b.Metric, b.MaxValue))
+}
+
+// checkMetrics returns the metrics in reps that exceed cfg.metricMaxes.
+// Metrics missing from a report are not reported.
+func checkMetrics(reps []*report, cfg *reportConfig) []metricBreach {
+	names := make([]string, 0, len(cfg.metricMaxes))
+	for name := range cfg.metricMaxes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var breaches []metricBreach
+	for _, rep := range reps {
+		for _, name := range names {
+			if v, ok := rep.Metrics[name]; ok && v > cfg.metricMaxes[name] {
+				breaches = append(breaches, metricBreach{stripUserinfo(rep.URL), name, v, cfg.metricMaxes[name]})
+			}
+		}
+	}
+	return breaches
+}
@@ -0,0 +1,59 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMetricMaxes(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want map[string]float64 // nil if error expected
+	}{
+		{"", map[string]float64{}},
+		{"lcp=2500ms", map[string]float64{"lcp": 2500}},
+		{"lcp=2500", map[string]float64{"lcp": 2500}},
+		{"lcp=2.5s, tbt=300ms,cls=0.1", map[string]float64{"lcp": 2500, "tbt": 300, "cls": 0.1}},
+		{cwvMetricMaxes, map[string]float64{"lcp": 2500, "cls": 0.1, "tbt": 200}},
+		{cwvMetricMaxes + ",lcp=4s", map[string]float64{"lcp": 4000, "cls": 0.1, "tbt": 200}},
+		{"lcp", nil},
+		{"bogus=100", nil},
+		{"lcp=abc", nil},
+		{"cls=0.1s", nil},
+		{"lcp=-5", nil},
+	} {
+		got, err := parseMetricMaxes(tc.in)
+		if tc.want == nil {
+			if err == nil {
+				t.Errorf("parseMetricMaxes(%q) unexpectedly succeeded", tc.in)
+			}
+		} else if err != nil {
+			t.Errorf("parseMetricMaxes(%q) failed: %v", tc.in, err)
+		} else if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("parseMetricMaxes(%q) = %v; want %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestCheckMetrics(t *testing.T) {
+	reps := []*report{
+		{URL: "https://example.org/a", Metrics: map[string]float64{"lcp": 3100, "cls": 0.05}},
+		{URL: "https://example.org/b", Metrics: map[string]float64{"lcp": 1200, "cls": 0.25}},
+		{URL: "https://example.org/c", Error: "failed"},
+	}
+	cfg := reportConfig{metricMaxes: map[string]float64{"lcp": 2500, "cls": 0.1}}
+	var got []string
+	for _, b := range checkMetrics(reps, &cfg) {
+		got = append(got, b.String())
+	}
+	want := []string{
+		"https://example.org/a: lcp is 3100 ms (max 2500 ms)",
+		"https://example.org/b: cls is 0.250 (max 0.100)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("checkMetrics() = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	pso "google.golang.org/api/pagespeedonline/v5"
+)
+
+// writeOutputDir writes each of the supplied reports to its own text and JSON
+// files within dir, which is created if it doesn't already exist.
+func writeOutputDir(dir string, reps []*report, cfg *reportConfig) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return writeOutputFiles(reps, cfg, func(name string, fn func(w io.Writer) error) error {
+		return writeFile(filepath.Join(dir, name), fn)
+	})
+}
+
+// writeOutputFiles calls create with the name of each per-URL file that should be
+// written for reps and a function that writes the file's contents.
+func writeOutputFiles(reps []*report, cfg *reportConfig,
+	create func(name string, fn func(w io.Writer) error) error) error {
+	cfg = withoutColor(cfg)
+	for _, rep := range reps {
+		base := urlFilename(rep.URL) + "-" + strategy(cfg)
+		if err := create(base+".txt", func(w io.Writer) error {
+			return writeReport(w, rep, cfg)
+		}); err != nil {
+			return err
+		}
+		if err := create(base+".json", func(w io.Writer) error {
+			return newJSONEncoder(w, cfg).Encode(withoutUserinfo([]*report{rep})[0])
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeRawResponse writes res, the API's response for u, to a JSON file within dir,
+// which is created if it doesn't already exist. The file's path is returned.
+func writeRawResponse(dir, u string, res *pso.PagespeedApiPagespeedResponseV5, cfg *reportConfig) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	p := filepath.Join(dir, urlFilename(u)+"-"+strategy(cfg)+".json")
+	return p, writeFile(p, func(w io.Writer) error { return newJSONEncoder(w, cfg).Encode(res) })
+}
+
+// screenshotExts maps from image MIME types to filename extensions.
+var screenshotExts = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// writeScreenshot decodes the final screenshot from res, the API's response for u,
+// and writes it to an image file within dir, which is created if it doesn't already exist.
+// The file's path is returned.
+func writeScreenshot(dir, u string, res *pso.PagespeedApiPagespeedResponseV5, cfg *reportConfig) (string, error) {
+	if res.LighthouseResult == nil {
+		return "", errors.New("missing Lighthouse result")
+	}
+	aud, ok := res.LighthouseResult.Audits["final-screenshot"]
+	if !ok || len(aud.Details) == 0 {
+		return "", errors.New("no final screenshot")
+	}
+	var details struct {
+		Data string `json:"data"` // data URI, e.g. "data:image/jpeg;base64,..."
+	}
+	if err := json.Unmarshal(aud.Details, &details); err != nil {
+		return "", err
+	}
+	typ, data, err := decodeDataURI(details.Data)
+	if err != nil {
+		return "", err
+	}
+	ext, ok := screenshotExts[typ]
+	if !ok {
+		return "", fmt.Errorf("unsupported image type %q", typ)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	p := filepath.Join(dir, urlFilename(u)+"-"+strategy(cfg)+ext)
+	return p, os.WriteFile(p, data, 0644)
+}
+
+// decodeDataURI decodes a base64-encoded data URI like "data:image/png;base64,...",
+// returning its MIME type and data.
+func decodeDataURI(uri string) (typ string, data []byte, err error) {
+	const prefix, suffix = "data:", ";base64"
+	i := strings.IndexByte(uri, ',')
+	if !strings.HasPrefix(uri, prefix) || i < 0 || !strings.HasSuffix(uri[:i], suffix) {
+		return "", nil, errors.New("not a base64 data URI")
+	}
+	data, err = base64.StdEncoding.DecodeString(uri[i+1:])
+	return strings.TrimSuffix(uri[len(prefix):i], suffix), data, err
+}
+
+// jsonOutput is the document written by writeJSON.
+type jsonOutput struct {
+	Metadata jsonMetadata `json:"metadata"`
+	Reports  []*report    `json:"reports"`
+}
+
+// jsonMetadata describes the run that produced a jsonOutput.
+type jsonMetadata struct {
+	Time     time.Time      `json:"time"`
+	Strategy string         `json:"strategy"`
+	Label    string         `json:"label,omitempty"`
+	Command  string         `json:"command,omitempty"` // set by -embed-command
+	APICalls *apiCallCounts `json:"apiCalls,omitempty"`
+}
+
+// writeJSON writes reps to w as a jsonOutput document.
+func writeJSON(w io.Writer, reps []*report, cfg *reportConfig) error {
+	return newJSONEncoder(w, cfg).Encode(jsonOutput{
+		Metadata: jsonMetadata{
+			Time:     cfg.startTime,
+			Strategy: strategy(cfg),
+			Label:    cfg.label,
+			Command:  cfg.command,
+			APICalls: cfg.apiCalls,
+		},
+		Reports: withoutUserinfo(reps),
+	})
+}
+
+// withoutUserinfo returns shallow copies of reps with userinfo removed from their URLs.
+func withoutUserinfo(reps []*report) []*report {
+	copies := make([]*report, len(reps))
+	for i, rep := range reps {
+		c := *rep
+		c.URL = stripUserinfo(rep.URL)
+		copies[i] = &c
+	}
+	return copies
+}
+
+// newJSONEncoder returns an encoder that writes to w, indenting its output if cfg.pretty is set.
+func newJSONEncoder(w io.Writer, cfg *reportConfig) *json.Encoder {
+	enc := json.NewEncoder(w)
+	if cfg.pretty {
+		enc.SetIndent("", "  ")
+	}
+	return enc
+}
+
+// writeFile creates or truncates the file at p and passes it to fn.
+func writeFile(p string, fn func(w io.Writer) error) error {
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	if err := fn(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeFileAtomic is like writeFile, but it writes to a temporary file that is
+// renamed to p on success and removed on failure, so a partial file isn't left at p.
+func writeFileAtomic(p string, fn func(w io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(p), "."+filepath.Base(p)+".*")
+	if err != nil {
+		return err
+	}
+	if err := fn(f); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), p)
+}
@@ -0,0 +1,57 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter limits the rate at which API calls are started, independently of
+// the number of workers. Calls are spaced evenly rather than allowed to burst.
+type rateLimiter struct {
+	interval time.Duration // minimum time between calls (0 for no limit)
+	mu       sync.Mutex
+	next     time.Time // earliest time at which the next call can start
+}
+
+// newRateLimiter returns a rateLimiter permitting perSec calls per second.
+// Calls are unlimited if perSec is not positive.
+func newRateLimiter(perSec float64) *rateLimiter {
+	l := &rateLimiter{}
+	if perSec > 0 {
+		l.interval = time.Duration(float64(time.Second) / perSec)
+	}
+	return l
+}
+
+// wait blocks until a call can be started or ctx is done,
+// returning ctx's error in the latter case.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil || l.interval <= 0 {
+		return err
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(l.interval)
+	l.mu.Unlock()
+
+	if start == now {
+		return nil
+	}
+	t := time.NewTimer(start.Sub(now))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
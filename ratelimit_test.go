@@ -0,0 +1,44 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	const (
+		perSec = 100
+		calls  = 6
+	)
+	l := newRateLimiter(perSec)
+	start := time.Now()
+	for i := 0; i < calls; i++ {
+		if err := l.wait(context.Background()); err != nil {
+			t.Fatalf("wait #%d failed: %v", i, err)
+		}
+	}
+	// The first call starts immediately and the rest are spaced out.
+	if elapsed, min := time.Since(start), (calls-1)*time.Second/perSec; elapsed < min {
+		t.Errorf("%d calls took %v; want at least %v", calls, elapsed, min)
+	}
+
+	// Waiting should be abandoned when the context is canceled.
+	l = newRateLimiter(0.001)
+	if err := l.wait(context.Background()); err != nil {
+		t.Fatal("First wait failed: ", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("wait with expiring context returned %v; want %v", err, context.DeadlineExceeded)
+	}
+
+	// A zero rate means no limit.
+	if err := newRateLimiter(0).wait(context.Background()); err != nil {
+		t.Error("wait without limit failed: ", err)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"io"
+	"regexp"
+	"strings"
+)
+
+// redactedValue replaces secrets removed by redactSecret.
+const redactedValue = "***"
+
+// keyParamRegexp matches the values of "key" query parameters in URLs.
+var keyParamRegexp = regexp.MustCompile(`([?&]key=)[^&\s"']*`)
+
+// userinfoRegexp matches the userinfo portions (e.g. "user:pass@") of plain URLs.
+// The authority ends at the first '/', '?', or '#', so an '@' after any of those
+// (e.g. in "https://example.org?email=a@b.com") isn't part of the userinfo.
+var userinfoRegexp = regexp.MustCompile(`://[^/?#@\s"'<>]*@`)
+
+// escapedUserinfoRegexp matches the userinfo portions of query-escaped URLs,
+// e.g. "%3A%2F%2Fuser%3Apass%40". Matches are checked by stripEscapedUserinfo.
+var escapedUserinfoRegexp = regexp.MustCompile(`(?i)%3A%2F%2F(?:[^%/?#@\s"'&<>]|%[0-9A-F]{2})*?%40`)
+
+// escapedAuthorityEndRegexp matches escaped characters that end a URL's authority.
+var escapedAuthorityEndRegexp = regexp.MustCompile(`(?i)%(2F|3F|23)`)
+
+// stripEscapedUserinfo is used with escapedUserinfoRegexp to remove userinfo
+// from a query-escaped URL.
+func stripEscapedUserinfo(m string) string {
+	const prefix = "%3A%2F%2F"
+	if escapedAuthorityEndRegexp.MatchString(m[len(prefix):]) {
+		return m // the "@" is after the authority
+	}
+	return m[:len(prefix)]
+}
+
+// redactSecret returns s with occurrences of secrets and the values of
+// "key" query parameters replaced by redactedValue. Userinfo is also removed from URLs.
+func redactSecret(s string, secrets ...string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, redactedValue)
+		}
+	}
+	s = userinfoRegexp.ReplaceAllString(s, "://")
+	s = escapedUserinfoRegexp.ReplaceAllStringFunc(s, stripEscapedUserinfo)
+	return keyParamRegexp.ReplaceAllString(s, "${1}"+redactedValue)
+}
+
+// redactWriter is an io.Writer that passes data through redactSecret before writing it to w.
+// It's intended to be passed to log.SetOutput, which writes each message in a single call.
+type redactWriter struct {
+	w       io.Writer
+	secrets []string
+}
+
+func (rw *redactWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(rw.w, redactSecret(string(p), rw.secrets...)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
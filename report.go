@@ -5,9 +5,13 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"google.golang.org/api/googleapi"
 	pso "google.golang.org/api/pagespeedonline/v5"
@@ -15,56 +19,131 @@ import (
 
 // report describes a Lighthouse report returned by PageSpeed Insights for a single URL.
 type report struct {
-	URL        string // canonicalized by PSI
-	Categories []category
+	URL        string             `json:"url"` // canonicalized by PSI
+	Categories []category         `json:"categories"`
+	Metrics    map[string]float64 `json:"metrics,omitempty"`    // lab metrics keyed by short name (e.g. "lcp"); see labMetrics
+	Label      string             `json:"label,omitempty"`      // from -label
+	Variant    string             `json:"variant,omitempty"`    // name of -variant used to construct URL, if any
+	ThirdParty *thirdPartySummary `json:"thirdParty,omitempty"` // nil if unavailable
+	Filmstrip  []filmstripFrame   `json:"filmstrip,omitempty"`  // only set if cfg.filmstrip is true
+
+	// Real-user metrics from the Chrome UX Report, or nil if unavailable.
+	FieldData       *fieldData `json:"fieldData,omitempty"`       // for the page
+	OriginFieldData *fieldData `json:"originFieldData,omitempty"` // for the page's origin
+
+	// These fields are only set if the report couldn't be fetched.
+	Error       string        `json:"error,omitempty"`       // error message
+	ErrorReason failureReason `json:"errorReason,omitempty"` // categorized reason for the failure
+
+	// Paths of files written by getReport for -raw-dir and -screenshot-dir, if any.
+	// These are used by writeArchive and aren't saved.
+	rawPath, screenshotPath string
 }
 
+// failureReason categorizes the reason for a failure to fetch a report.
+type failureReason string
+
+const (
+	failureTimeout failureReason = "timeout" // request or analysis timed out
+	failureQuota   failureReason = "quota"   // rate limit or quota exceeded
+	failureRuntime failureReason = "runtime" // Lighthouse failed to analyze the page
+	failureDecode  failureReason = "decode"  // API response was unparseable or incomplete
+	failureCancel  failureReason = "cancel"  // run was interrupted before the report was fetched
+	failureOther   failureReason = "other"
+)
+
 // category describes a category ("Performance", "Accessibility", etc.) within a Lighthouse report.
 type category struct {
-	Title  string // e.g. "Performance"
-	Abbrev string // e.g. "Perf"
-	Score  int    // [0, 100]
-	Audits []audit
+	ID         string  `json:"id"`         // e.g. "performance"
+	Title      string  `json:"title"`      // e.g. "Performance"
+	Abbrev     string  `json:"abbrev"`     // e.g. "Perf"
+	Score      int     `json:"score"`      // [0, 100]
+	ScoreFloat float64 `json:"scoreFloat"` // unrounded score from PSI in [0, 1] or -1 if unset
+	Audits     []audit `json:"audits,omitempty"`
 }
 
 // audit describes an audit (e.g. "Serve images in next-gen formats") within a Lighthouse report.
 type audit struct {
-	Title   string
-	Score   int        // [0, 100] or -1 if unset
-	Value   string     // optional
-	Details [][]string // tabular details about the audit
+	ID         string       `json:"id"` // e.g. "uses-webp-images"
+	Title      string       `json:"title"`
+	Score      int          `json:"score"`             // [0, 100] or -1 if unset
+	ScoreFloat float64      `json:"scoreFloat"`        // unrounded score from PSI in [0, 1] or -1 if unset
+	Weight     float64      `json:"weight,omitempty"`  // weight in category's score
+	Value      string       `json:"value,omitempty"`   // optional
+	Details    [][][]string `json:"details,omitempty"` // tables of details about the audit
+
+	// Estimated savings from opportunity audits, or 0 if unavailable.
+	SavingsMs    float64 `json:"savingsMs,omitempty"`
+	SavingsBytes float64 `json:"savingsBytes,omitempty"`
+}
+
+// thirdPartySummary describes the total cost of third-party resources loaded by a page.
+type thirdPartySummary struct {
+	TransferSize float64 `json:"transferSize"` // bytes
+	BlockingTime float64 `json:"blockingTime"` // milliseconds of main-thread blocking
+}
+
+// filmstripFrame describes a frame from the "screenshot-thumbnails" audit.
+type filmstripFrame struct {
+	Timing  float64 `json:"timing"`  // milliseconds since navigation start
+	Changed bool    `json:"changed"` // frame differs from the previous frame (always true for the first frame)
 }
 
 // readReport returns the Lighthouse report from a PageSpeed Insights API response.
-func readReport(res *pso.PagespeedApiPagespeedResponseV5) (*report, error) {
+func readReport(res *pso.PagespeedApiPagespeedResponseV5, cfg *reportConfig) (*report, error) {
 	rep := &report{URL: res.Id}
 	lhr := res.LighthouseResult
+	if lhr == nil || lhr.Categories == nil {
+		return nil, &decodeError{errors.New("missing Lighthouse result")}
+	}
+	rep.Metrics = getMetrics(lhr)
+	rep.FieldData = getFieldData(res.LoadingExperience)
+	rep.OriginFieldData = getFieldData(res.OriginLoadingExperience)
+	if aud, ok := lhr.Audits["third-party-summary"]; ok {
+		rep.ThirdParty = getThirdPartySummary(aud.Details)
+	}
+	if aud, ok := lhr.Audits["screenshot-thumbnails"]; ok && cfg.filmstrip {
+		rep.Filmstrip = getFilmstrip(aud.Details)
+	}
+	requested := requestedCategories(cfg)
 	for _, lhrCat := range []*pso.LighthouseCategoryV5{
 		// This matches the order in Chrome DevTools.
 		lhr.Categories.Performance,
 		lhr.Categories.Accessibility,
 		lhr.Categories.BestPractices,
 		lhr.Categories.Seo,
-		lhr.Categories.Pwa,
+		lhr.Categories.Pwa, // removed in Lighthouse 12
 	} {
-		if lhrCat == nil {
-			continue
+		if lhrCat == nil || !hasString(requested, lhrCat.Id) {
+			continue // not requested or not supported by the API
 		}
 		cat := category{
-			Title:  lhrCat.Title,
-			Abbrev: categoryAbbrev(lhrCat.Id),
-			Score:  score100(lhrCat.Score),
+			ID:         lhrCat.Id,
+			Title:      lhrCat.Title,
+			Abbrev:     categoryAbbrev(lhrCat.Id),
+			Score:      score100(lhrCat.Score),
+			ScoreFloat: scoreFloat(lhrCat.Score),
 		}
 		for _, ar := range lhrCat.AuditRefs {
 			lhrAudit, ok := lhr.Audits[ar.Id]
 			if !ok {
 				return nil, fmt.Errorf("category %q is missing audit %q", cat.Title, ar.Id)
 			}
-			cat.Audits = append(cat.Audits, audit{
-				Title:   lhrAudit.Title,
-				Score:   score100(lhrAudit.Score),
-				Details: getDetails(lhrAudit.Details),
-			})
+			if cfg.strict {
+				if err := checkDetails(lhrAudit.Details); err != nil {
+					return nil, &detailsError{ar.Id, err, cfg.strictRetry}
+				}
+			}
+			aud := audit{
+				ID:         ar.Id,
+				Title:      lhrAudit.Title,
+				Score:      score100(lhrAudit.Score),
+				ScoreFloat: scoreFloat(lhrAudit.Score),
+				Weight:     ar.Weight,
+				Details:    getDetails(lhrAudit.Details, cfg),
+			}
+			aud.SavingsMs, aud.SavingsBytes = getSavings(lhrAudit.Details)
+			cat.Audits = append(cat.Audits, aud)
 		}
 		rep.Categories = append(rep.Categories, cat)
 	}
@@ -72,6 +151,230 @@ func readReport(res *pso.PagespeedApiPagespeedResponseV5) (*report, error) {
 	return rep, nil
 }
 
+// summaryCategories returns the union of the categories in reps (without audits),
+// in the order in which they first appear.
+func summaryCategories(reps []*report) []category {
+	var cats []category
+	seen := make(map[string]struct{})
+	for _, rep := range reps {
+		for _, cat := range rep.Categories {
+			if _, ok := seen[cat.ID]; !ok {
+				seen[cat.ID] = struct{}{}
+				cats = append(cats, category{ID: cat.ID, Title: cat.Title, Abbrev: cat.Abbrev})
+			}
+		}
+	}
+	return cats
+}
+
+// findCategory returns the category in rep with the supplied ID, or nil if it isn't present.
+func findCategory(rep *report, id string) *category {
+	for i := range rep.Categories {
+		if rep.Categories[i].ID == id {
+			return &rep.Categories[i]
+		}
+	}
+	return nil
+}
+
+// meanScore returns the mean score (rounded to the nearest integer) of the category with
+// the supplied ID across reps. Reports without the category or without a score for it
+// are skipped. The number of reports contributing to the mean is also returned.
+func meanScore(reps []*report, id string) (mean, n int) {
+	var sum int
+	for _, rep := range reps {
+		if cat := findCategory(rep, id); cat != nil && cat.Score >= 0 {
+			sum += cat.Score
+			n++
+		}
+	}
+	if n == 0 {
+		return -1, 0
+	}
+	return int(math.Round(float64(sum) / float64(n))), n
+}
+
+// scoreRange returns the minimum and maximum scores of the category with the
+// supplied ID across reps, skipping reports as in meanScore. The number of reports
+// contributing to the range is also returned.
+func scoreRange(reps []*report, id string) (min, max, n int) {
+	min, max = -1, -1
+	for _, rep := range reps {
+		if cat := findCategory(rep, id); cat != nil && cat.Score >= 0 {
+			if n == 0 || cat.Score < min {
+				min = cat.Score
+			}
+			if n == 0 || cat.Score > max {
+				max = cat.Score
+			}
+			n++
+		}
+	}
+	return min, max, n
+}
+
+// parseScoreSort parses a -sort value naming a category by its lowercase abbreviation
+// (e.g. "perf" or "a11y") or ID, optionally prefixed by '-' for descending order.
+// ok is false if s doesn't name a category.
+func parseScoreSort(s string) (id string, desc, ok bool) {
+	if strings.HasPrefix(s, "-") {
+		s, desc = s[1:], true
+	}
+	for _, id := range knownCategories {
+		if s == id || s == strings.ToLower(categoryAbbrev(id)) {
+			return id, desc, true
+		}
+	}
+	return "", false, false
+}
+
+// sortByScore stably sorts reps by the score of the category with the supplied ID,
+// in ascending order unless desc is true. Reports without a score for the category
+// (including failed reports) are placed last regardless of the order.
+func sortByScore(reps []*report, id string, desc bool) {
+	score := func(rep *report) int {
+		if cat := findCategory(rep, id); cat != nil {
+			return cat.Score
+		}
+		return -1
+	}
+	sort.SliceStable(reps, func(i, j int) bool {
+		si, sj := score(reps[i]), score(reps[j])
+		if si < 0 || sj < 0 {
+			return si >= 0 && sj < 0
+		}
+		if desc {
+			return si > sj
+		}
+		return si < sj
+	})
+}
+
+// categoryEmpty returns true if cat has no score or none of its audits are scored
+// (e.g. the PWA category for a page that isn't a PWA may contain only manual audits).
+func categoryEmpty(cat *category) bool {
+	if cat.Score < 0 {
+		return true
+	}
+	for _, aud := range cat.Audits {
+		if aud.Score >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// dropEmptyCategories removes categories for which categoryEmpty returns true from rep.
+func dropEmptyCategories(rep *report) {
+	cats := rep.Categories[:0]
+	for _, cat := range rep.Categories {
+		if !categoryEmpty(&cat) {
+			cats = append(cats, cat)
+		}
+	}
+	rep.Categories = cats
+}
+
+// completenessChecker checks that reports contain all expected data.
+// It is safe to call from multiple goroutines.
+type completenessChecker struct {
+	cats   []string       // expected category IDs
+	mu     sync.Mutex     // protects audits
+	audits map[string]int // expected audit count keyed by category ID
+}
+
+func newCompletenessChecker(cats []string) *completenessChecker {
+	return &completenessChecker{cats: cats}
+}
+
+// check returns a decodeError if rep is missing any expected categories
+// or category scores, or contains fewer audits in any category than the first
+// complete report that was passed to check.
+func (c *completenessChecker) check(rep *report) error {
+	incomplete := func(format string, args ...interface{}) error {
+		return &decodeError{fmt.Errorf("incomplete report: "+format, args...)}
+	}
+	counts := make(map[string]int, len(c.cats))
+	for _, id := range c.cats {
+		cat := findCategory(rep, id)
+		if cat == nil && id == "pwa" {
+			continue // PWA was removed in Lighthouse 12, so it may be missing even if requested
+		} else if cat == nil {
+			return incomplete("missing category %q", id)
+		} else if cat.Score < 0 {
+			return incomplete("missing score for category %q", id)
+		}
+		counts[id] = len(cat.Audits)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.audits == nil {
+		c.audits = counts
+		return nil
+	}
+	for _, id := range c.cats {
+		if counts[id] < c.audits[id] {
+			return incomplete("category %q has %d audit(s); expected %d", id, counts[id], c.audits[id])
+		}
+	}
+	return nil
+}
+
+// auditFailed returns true if aud has a score that isn't perfect.
+// The unrounded score is used if available so that e.g. 0.996 isn't treated as perfect.
+func auditFailed(aud *audit) bool {
+	if aud.Score < 0 {
+		return false
+	}
+	if f := unroundedScore(aud.Score, aud.ScoreFloat); f >= 0 {
+		return f < 1
+	}
+	return aud.Score < 100
+}
+
+// showAudit returns true if aud should be printed in reports according to
+// cfg.audits, cfg.auditFilter, and cfg.auditExclude.
+func showAudit(aud *audit, cfg *reportConfig) bool {
+	switch {
+	case cfg.audits == auditsNone:
+		return false
+	case cfg.audits == auditsFailed && !auditFailed(aud):
+		return false
+	case len(cfg.auditFilter) > 0 && !auditMatches(aud, cfg.auditFilter):
+		return false
+	case auditMatches(aud, cfg.auditExclude):
+		return false
+	}
+	return true
+}
+
+// auditMatches returns true if aud's ID is in pats or its title contains
+// one of pats (ignoring case).
+func auditMatches(aud *audit, pats []string) bool {
+	title := strings.ToLower(aud.Title)
+	for _, p := range pats {
+		if aud.ID == p || strings.Contains(title, strings.ToLower(p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// scoreBand returns the Lighthouse color band ("pass", "average", or "fail")
+// for a score in [0, 100], or an empty string if the score is unset.
+func scoreBand(score int) string {
+	switch {
+	case score < 0:
+		return ""
+	case score < 50:
+		return "fail"
+	case score < 90:
+		return "average"
+	}
+	return "pass"
+}
+
 // score100 converts the supplied float64 in [0, 1] to an int in [0, 100].
 // -1 is returned if score is not a float64 (typically because it's nil instead).
 func score100(score interface{}) int {
@@ -82,6 +385,68 @@ func score100(score interface{}) int {
 	return int(math.Round(f * 100))
 }
 
+// scoreFloat returns score if it's a float64 (typically in [0, 1]) or -1 otherwise.
+func scoreFloat(score interface{}) float64 {
+	if f, ok := score.(float64); ok {
+		return f
+	}
+	return -1
+}
+
+// unroundedScore returns raw, a ScoreFloat field corresponding to score (in [0, 100]).
+// Reports saved before ScoreFloat was added have 0 instead, so score/100 is returned
+// if raw is 0 but score isn't. -1 is returned if raw is -1.
+func unroundedScore(score int, raw float64) float64 {
+	if raw == 0 && score > 0 {
+		return float64(score) / 100
+	}
+	return raw
+}
+
+// formatScore formats the supplied score for display, using cfg.scoreDecimals
+// decimal places if raw (the unrounded score in [0, 1]) is set.
+func formatScore(score int, raw float64, cfg *reportConfig) string {
+	if f := unroundedScore(score, raw); cfg.scoreDecimals > 0 && f >= 0 {
+		return strconv.FormatFloat(f*100, 'f', cfg.scoreDecimals, 64)
+	}
+	return strconv.Itoa(score)
+}
+
+// knownCategories lists the IDs of categories returned by PageSpeed Insights.
+var knownCategories = []string{"performance", "accessibility", "best-practices", "seo", "pwa"}
+
+// defaultCategories lists the IDs of categories that are requested by default.
+// The PWA category was removed in Lighthouse 12, so it's only requested via -pwa.
+var defaultCategories = []string{"performance", "best-practices", "accessibility", "seo"}
+
+// parseCategories parses a comma-separated list of category IDs,
+// returning an error if any of the IDs are unknown.
+func parseCategories(s string) ([]string, error) {
+	var ids []string
+	for _, id := range splitList(s) {
+		if !knownCategory(id) {
+			return nil, fmt.Errorf("unknown category %q (want one of %s)", id, strings.Join(knownCategories, ", "))
+		}
+		if !hasString(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("no categories")
+	}
+	return ids, nil
+}
+
+// knownCategory returns true if id is in knownCategories.
+func knownCategory(id string) bool {
+	for _, c := range knownCategories {
+		if c == id {
+			return true
+		}
+	}
+	return false
+}
+
 // categoryAbbrev returns a short abbreviation for pso.LighthouseCategoryV5.Id.
 func categoryAbbrev(id string) string {
 	switch id {
@@ -99,18 +464,43 @@ func categoryAbbrev(id string) string {
 	return id
 }
 
-// getDetails tries to extract tabular data from pso.LighthouseAuditResultV5.Details.
-func getDetails(raw googleapi.RawMessage) [][]string {
+// getDetails tries to extract tables of data from pso.LighthouseAuditResultV5.Details.
+// Lists containing multiple details objects are flattened into sequential tables.
+func getDetails(raw googleapi.RawMessage, cfg *reportConfig) [][][]string {
+	if len(raw) == 0 {
+		return nil
+	}
+	var list struct {
+		Type  string            `json:"type"`
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(raw, &list); err == nil && list.Type == "list" {
+		var tables [][][]string
+		for _, item := range list.Items {
+			tables = append(tables, getDetails(googleapi.RawMessage(item), cfg)...)
+		}
+		return tables
+	}
+	if table := getTable(raw, cfg); len(table) > 0 {
+		return [][][]string{table}
+	}
+	return nil
+}
+
+// getTable tries to extract a single table of data from a details object.
+func getTable(raw googleapi.RawMessage, cfg *reportConfig) [][]string {
 	if len(raw) == 0 {
 		return nil
 	}
 	var details struct {
 		Type     string `json:"type"`
 		Headings []struct {
-			Key      string `json:"key"`
-			Text     string `json:"text"`
-			Label    string `json:"label"`
-			ItemType string `json:"itemType"`
+			Key         string  `json:"key"`
+			Text        string  `json:"text"`
+			Label       string  `json:"label"`
+			ItemType    string  `json:"itemType"`    // older Lighthouse versions
+			ValueType   string  `json:"valueType"`   // newer Lighthouse versions
+			Granularity float64 `json:"granularity"` // e.g. 0.001 for numeric values
 		} `json:"headings"`
 		Items []map[string]interface{} `json:"items"`
 	}
@@ -121,7 +511,8 @@ func getDetails(raw googleapi.RawMessage) [][]string {
 		return nil
 	}
 
-	var headings, keys, units []string // names, keys, and units for each column
+	var headings, keys, units []string // names, keys, and value types for each column
+	var grans []float64                // granularity for each column
 	for _, h := range details.Headings {
 		var name string
 		if h.Text != "" {
@@ -131,12 +522,12 @@ func getDetails(raw googleapi.RawMessage) [][]string {
 		}
 		headings = append(headings, strings.TrimSpace(name))
 
-		var un string
-		switch h.ItemType {
-		case "ms", "bytes":
-			un = h.ItemType
+		typ := h.ValueType
+		if typ == "" {
+			typ = h.ItemType
 		}
-		units = append(units, un)
+		units = append(units, typ)
+		grans = append(grans, h.Granularity)
 
 		keys = append(keys, h.Key)
 	}
@@ -151,10 +542,7 @@ func getDetails(raw googleapi.RawMessage) [][]string {
 				case string:
 					val = strings.TrimSpace(vt)
 				case float64:
-					val = strings.TrimSuffix(fmt.Sprintf("%.1f", vt), ".0")
-					if un := units[i]; un != "" {
-						val += " " + un
-					}
+					val = formatDetailNumber(vt, units[i], grans[i])
 				case map[string]interface{}:
 					if s, ok := vt["snippet"].(string); ok {
 						val = s
@@ -169,8 +557,194 @@ func getDetails(raw googleapi.RawMessage) [][]string {
 			}
 			row = append(row, val)
 		}
+		if cfg.detailSizes {
+			addURLSize(row, keys, item)
+		}
 		rows = append(rows, row)
 	}
 
 	return rows
 }
+
+// knownDetailsTypes lists the details types that checkDetails accepts.
+// Types other than "table", "opportunity", and "list" are ignored by getDetails.
+var knownDetailsTypes = map[string]bool{
+	"table":                true,
+	"opportunity":          true,
+	"list":                 true,
+	"checklist":            true,
+	"criticalrequestchain": true,
+	"debugdata":            true,
+	"filmstrip":            true,
+	"full-page-screenshot": true,
+	"network-tree":         true,
+	"screenshot":           true,
+	"treemap-data":         true,
+}
+
+// checkDetails returns an error if getDetails wouldn't be able to structurally parse raw,
+// i.e. if it has an unknown type or contains items that would be stringified.
+func checkDetails(raw googleapi.RawMessage) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	var details struct {
+		Type     string `json:"type"`
+		Headings []struct {
+			Key string `json:"key"`
+		} `json:"headings"`
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(raw, &details); err != nil {
+		return err
+	}
+	if !knownDetailsTypes[details.Type] {
+		return fmt.Errorf("unknown details type %q", details.Type)
+	}
+	switch details.Type {
+	case "list":
+		for _, item := range details.Items {
+			if err := checkDetails(googleapi.RawMessage(item)); err != nil {
+				return err
+			}
+		}
+	case "table", "opportunity":
+		for i, rawItem := range details.Items {
+			var item map[string]interface{}
+			if err := json.Unmarshal(rawItem, &item); err != nil {
+				return fmt.Errorf("item %d: %v", i, err)
+			}
+			for _, h := range details.Headings {
+				switch v := item[h.Key].(type) {
+				case nil, string, float64, bool:
+				case map[string]interface{}:
+					if _, ok := v["snippet"].(string); ok {
+						continue
+					}
+					if _, ok := v["url"].(string); ok {
+						continue
+					}
+					return fmt.Errorf("item %d: unknown %q object", i, h.Key)
+				default:
+					return fmt.Errorf("item %d: unknown %q value of type %T", i, h.Key, v)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// getSavings returns the estimated savings in milliseconds and bytes from the details
+// of an opportunity audit. Zero is returned for missing values.
+func getSavings(raw googleapi.RawMessage) (ms, bytes float64) {
+	if len(raw) == 0 {
+		return 0, 0
+	}
+	var details struct {
+		OverallSavingsMs    float64 `json:"overallSavingsMs"`
+		OverallSavingsBytes float64 `json:"overallSavingsBytes"`
+	}
+	if err := json.Unmarshal(raw, &details); err != nil {
+		return 0, 0
+	}
+	return details.OverallSavingsMs, details.OverallSavingsBytes
+}
+
+// getThirdPartySummary sums the per-entity costs in the details of the
+// "third-party-summary" audit. nil is returned if the details can't be parsed.
+func getThirdPartySummary(raw googleapi.RawMessage) *thirdPartySummary {
+	if len(raw) == 0 {
+		return nil
+	}
+	var details struct {
+		Items []struct {
+			TransferSize float64 `json:"transferSize"`
+			BlockingTime float64 `json:"blockingTime"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(raw, &details); err != nil {
+		return nil
+	}
+	var sum thirdPartySummary
+	for _, item := range details.Items {
+		sum.TransferSize += item.TransferSize
+		sum.BlockingTime += item.BlockingTime
+	}
+	return &sum
+}
+
+// getFilmstrip returns the frames from the details of the "screenshot-thumbnails" audit.
+// The frames' image data is compared to detect visual changes but isn't retained.
+func getFilmstrip(raw googleapi.RawMessage) []filmstripFrame {
+	if len(raw) == 0 {
+		return nil
+	}
+	var details struct {
+		Items []struct {
+			Timing float64 `json:"timing"`
+			Data   string  `json:"data"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(raw, &details); err != nil {
+		return nil
+	}
+	var frames []filmstripFrame
+	for i, item := range details.Items {
+		frames = append(frames, filmstripFrame{
+			Timing:  item.Timing,
+			Changed: i == 0 || item.Data != details.Items[i-1].Data,
+		})
+	}
+	return frames
+}
+
+// formatDetailNumber formats a numeric value from an audit details table.
+// typ is the column's Lighthouse value type, e.g. "ms", "bytes", or "numeric",
+// and gran is its granularity (e.g. 0.001), or 0 if unspecified.
+func formatDetailNumber(v float64, typ string, gran float64) string {
+	switch typ {
+	case "ms", "timespanMs":
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", v), ".0") + " ms"
+	case "bytes":
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", v), ".0") + " bytes"
+	case "numeric":
+		// Unitless values can be small ratios like layout shift scores or
+		// counts like numbers of requests, so use the column's granularity.
+		if gran <= 0 {
+			return formatRatio(v)
+		}
+		return formatGranular(v, gran)
+	}
+	return strings.TrimSuffix(fmt.Sprintf("%.1f", v), ".0")
+}
+
+// formatGranular rounds v to the nearest multiple of gran (e.g. 0.5 or 10) and formats
+// it with as many decimal places as are needed to represent gran.
+func formatGranular(v, gran float64) string {
+	var decimals int
+	if s := strconv.FormatFloat(gran, 'f', -1, 64); strings.Contains(s, ".") {
+		decimals = len(s) - strings.IndexByte(s, '.') - 1
+	}
+	return strconv.FormatFloat(math.Round(v/gran)*gran, 'f', decimals, 64)
+}
+
+// formatRatio formats a small unitless value like a Cumulative Layout Shift score.
+func formatRatio(v float64) string {
+	return fmt.Sprintf("%.3f", v)
+}
+
+// addURLSize appends the resource size from item (e.g. " (120 KiB)") to the "url" column
+// in row, if present. keys contains the item key corresponding to each column in row.
+func addURLSize(row, keys []string, item map[string]interface{}) {
+	for i, key := range keys {
+		if key != "url" || row[i] == "" {
+			continue
+		}
+		for _, sk := range []string{"transferSize", "totalBytes", "resourceSize"} {
+			if size, ok := item[sk].(float64); ok {
+				row[i] += " (" + formatBytes(size) + ")"
+				return
+			}
+		}
+	}
+}
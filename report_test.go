@@ -0,0 +1,413 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+	pso "google.golang.org/api/pagespeedonline/v5"
+)
+
+func TestGetDetails(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		raw  string
+		want [][][]string
+	}{
+		{"empty", ``, nil},
+		{"no_items", `{"type":"table","headings":[{"key":"url","label":"URL"}],"items":[]}`, nil},
+		{
+			"table",
+			`{"type":"table","headings":[{"key":"url","label":"URL"},{"key":"wastedMs","label":"Savings","itemType":"ms"}],
+			  "items":[{"url":"https://example.org/a.js","wastedMs":150},{"url":"https://example.org/b.js","wastedMs":20.25}]}`,
+			[][][]string{{
+				{"URL", "Savings"},
+				{"https://example.org/a.js", "150 ms"},
+				{"https://example.org/b.js", "20.2 ms"},
+			}},
+		},
+		{
+			"list",
+			`{"type":"list","items":[
+			   {"type":"table","headings":[{"key":"node","label":"Element"}],"items":[{"node":{"snippet":"<img>"}}]},
+			   {"type":"debugdata","items":[{"foo":"bar"}]},
+			   {"type":"table","headings":[{"key":"url","text":"URL"}],"items":[{"url":"https://example.org/"}]}
+			 ]}`,
+			[][][]string{
+				{{"Element"}, {"<img>"}},
+				{{"URL"}, {"https://example.org/"}},
+			},
+		},
+	} {
+		if got := getDetails(googleapi.RawMessage(tc.raw), &reportConfig{}); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%v: getDetails() = %q; want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCheckDetails(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		raw  string
+		ok   bool
+	}{
+		{"empty", ``, true},
+		{"table", `{"type":"table","headings":[{"key":"node"},{"key":"ms"}],
+		   "items":[{"node":{"snippet":"<img>"},"ms":12},{"node":{"url":"https://example.org/"}}]}`, true},
+		{"ignored", `{"type":"debugdata","items":[{"foo":["bar"]}]}`, true},
+		{"list", `{"type":"list","items":[{"type":"screenshot"},{"type":"table","items":[]}]}`, true},
+		{"unknown_type", `{"type":"hologram"}`, false},
+		{"unknown_in_list", `{"type":"list","items":[{"type":"hologram"}]}`, false},
+		{"unknown_object", `{"type":"table","headings":[{"key":"node"}],"items":[{"node":{"foo":"bar"}}]}`, false},
+		{"array_value", `{"type":"opportunity","headings":[{"key":"urls"}],"items":[{"urls":["a","b"]}]}`, false},
+		{"bad_json", `{"type":"table","items":{}}`, false},
+	} {
+		if err := checkDetails(googleapi.RawMessage(tc.raw)); (err == nil) != tc.ok {
+			t.Errorf("%v: checkDetails() = %v; want ok = %v", tc.name, err, tc.ok)
+		}
+	}
+}
+
+func TestGetDetails_Sizes(t *testing.T) {
+	raw := `{"type":"opportunity","headings":[
+	  {"key":"url","valueType":"url","label":"URL"},
+	  {"key":"wastedBytes","valueType":"bytes","label":"Potential Savings"}],
+	  "items":[
+	    {"url":"https://cdn.example.org/x.js","totalBytes":122880,"wastedBytes":61440},
+	    {"url":"https://cdn.example.org/y.js","wastedBytes":1024}]}`
+	want := [][][]string{{
+		{"URL", "Potential Savings"},
+		{"https://cdn.example.org/x.js (120 KiB)", "61440 bytes"},
+		{"https://cdn.example.org/y.js", "1024 bytes"},
+	}}
+	cfg := reportConfig{detailSizes: true}
+	if got := getDetails(googleapi.RawMessage(raw), &cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("getDetails() = %q; want %q", got, want)
+	}
+}
+
+func TestGetSavings(t *testing.T) {
+	for _, tc := range []struct {
+		raw       string
+		ms, bytes float64
+	}{
+		{``, 0, 0},
+		{`{"type":"opportunity","overallSavingsMs":1200,"overallSavingsBytes":348160,"items":[]}`, 1200, 348160},
+		{`{"type":"opportunity","overallSavingsMs":450}`, 450, 0},
+		{`{"type":"table","items":[]}`, 0, 0},
+	} {
+		if ms, bytes := getSavings(googleapi.RawMessage(tc.raw)); ms != tc.ms || bytes != tc.bytes {
+			t.Errorf("getSavings(%q) = (%v, %v); want (%v, %v)", tc.raw, ms, bytes, tc.ms, tc.bytes)
+		}
+	}
+}
+
+func TestFormatDetailNumber(t *testing.T) {
+	for _, tc := range []struct {
+		v    float64
+		typ  string
+		gran float64
+		want string
+	}{
+		{1234, "", 0, "1234"},
+		{12.34, "", 0, "12.3"},
+		{1234.5, "ms", 0, "1234.5 ms"},
+		{1234, "timespanMs", 0, "1234 ms"},
+		{61440, "bytes", 0, "61440 bytes"},
+		{0.05, "numeric", 0.001, "0.050"},
+		{0.1234, "numeric", 0.001, "0.123"},
+		{0, "numeric", 0.001, "0.000"},
+		{0.1234, "numeric", 0.01, "0.12"},
+		{0.05, "numeric", 0, "0.050"},
+		{0.25, "numeric", 0.5, "0.5"},
+		{0.7, "numeric", 0.5, "0.5"},
+		{1.8, "numeric", 0.5, "2.0"},
+		{42, "numeric", 1, "42"},
+		{41.6, "numeric", 1, "42"},
+		{1234, "numeric", 10, "1230"},
+	} {
+		if got := formatDetailNumber(tc.v, tc.typ, tc.gran); got != tc.want {
+			t.Errorf("formatDetailNumber(%v, %q, %v) = %q; want %q", tc.v, tc.typ, tc.gran, got, tc.want)
+		}
+	}
+}
+
+func TestGetDetails_LayoutShift(t *testing.T) {
+	raw := `{"type":"table","headings":[
+	  {"key":"node","valueType":"node","label":"Element"},
+	  {"key":"score","valueType":"numeric","granularity":0.001,"label":"Layout shift score"}],
+	  "items":[{"node":{"snippet":"<div class=\"ad\">"},"score":0.05}]}`
+	want := [][][]string{{
+		{"Element", "Layout shift score"},
+		{`<div class="ad">`, "0.050"},
+	}}
+	if got := getDetails(googleapi.RawMessage(raw), &reportConfig{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("getDetails() = %q; want %q", got, want)
+	}
+}
+
+func TestFormatScore(t *testing.T) {
+	for _, tc := range []struct {
+		score    int
+		raw      float64
+		decimals int
+		want     string
+	}{
+		{90, 0.895, 0, "90"},
+		{90, 0.895, 1, "89.5"},
+		{90, 0.895, 2, "89.50"},
+		{100, 1, 1, "100.0"},
+		{90, -1, 1, "90"},
+		{90, 0, 1, "90.0"}, // saved before ScoreFloat was added
+		{0, 0, 1, "0.0"},
+	} {
+		cfg := reportConfig{scoreDecimals: tc.decimals}
+		if got := formatScore(tc.score, tc.raw, &cfg); got != tc.want {
+			t.Errorf("formatScore(%d, %v) with %d decimal(s) = %q; want %q",
+				tc.score, tc.raw, tc.decimals, got, tc.want)
+		}
+	}
+}
+
+func TestGetThirdPartySummary(t *testing.T) {
+	raw := `{"type":"table","headings":[],"items":[
+	  {"entity":{"type":"link","text":"Google Tag Manager"},"transferSize":102400,"blockingTime":120.5,"mainThreadTime":300},
+	  {"entity":"Facebook","transferSize":327680,"blockingTime":59.5},
+	  {"entity":"Other"}]}`
+	want := &thirdPartySummary{TransferSize: 430080, BlockingTime: 180}
+	if got := getThirdPartySummary(googleapi.RawMessage(raw)); !reflect.DeepEqual(got, want) {
+		t.Errorf("getThirdPartySummary() = %+v; want %+v", got, want)
+	}
+	if got := getThirdPartySummary(nil); got != nil {
+		t.Errorf("getThirdPartySummary(nil) = %+v; want nil", got)
+	}
+}
+
+func TestAuditFailed(t *testing.T) {
+	for _, tc := range []struct {
+		aud  audit
+		want bool
+	}{
+		{audit{Score: 100, ScoreFloat: 1}, false},
+		{audit{Score: 100, ScoreFloat: 0.996}, true}, // rounds to 100
+		{audit{Score: 99, ScoreFloat: 0.99}, true},
+		{audit{Score: 0, ScoreFloat: 0}, true},
+		{audit{Score: -1, ScoreFloat: -1}, false},
+		{audit{Score: 100}, false}, // unrounded score unavailable
+		{audit{Score: 50}, true},
+		{audit{Score: 100, ScoreFloat: -1}, false},
+		{audit{Score: 50, ScoreFloat: -1}, true},
+	} {
+		if got := auditFailed(&tc.aud); got != tc.want {
+			t.Errorf("auditFailed(%+v) = %v; want %v", tc.aud, got, tc.want)
+		}
+	}
+}
+
+func TestCompletenessChecker(t *testing.T) {
+	mkrep := func(perfScore, perfAudits, seoAudits int) *report {
+		perf := category{ID: "performance", Score: perfScore, Audits: make([]audit, perfAudits)}
+		seo := category{ID: "seo", Score: 100, Audits: make([]audit, seoAudits)}
+		return &report{Categories: []category{perf, seo}}
+	}
+	checker := newCompletenessChecker([]string{"performance", "seo"})
+	for i, tc := range []struct {
+		rep *report
+		ok  bool
+	}{
+		{&report{}, false},
+		{mkrep(-1, 5, 5), false},  // missing score
+		{mkrep(90, 10, 5), true},  // first complete report
+		{mkrep(90, 9, 5), false},  // missing perf audit
+		{mkrep(90, 10, 4), false}, // missing SEO audit
+		{mkrep(90, 10, 5), true},  // same as first
+		{mkrep(90, 11, 5), true},  // extra audits are okay
+		{&report{Categories: []category{{ID: "performance", Score: 90}}}, false}, // missing SEO
+	} {
+		err := checker.check(tc.rep)
+		if tc.ok && err != nil {
+			t.Errorf("Report %d: check failed: %v", i, err)
+		} else if !tc.ok && err == nil {
+			t.Errorf("Report %d: check unexpectedly succeeded", i)
+		}
+	}
+}
+
+func TestParseCategories(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want []string // nil if error expected
+	}{
+		{"performance", []string{"performance"}},
+		{" seo , performance,seo", []string{"seo", "performance"}},
+		{"best-practices,pwa", []string{"best-practices", "pwa"}},
+		{"perf", nil},
+		{"performance,SEO", nil},
+		{"", nil},
+	} {
+		got, err := parseCategories(tc.in)
+		if tc.want == nil && err == nil {
+			t.Errorf("parseCategories(%q) = %q; want error", tc.in, got)
+		} else if tc.want != nil && err != nil {
+			t.Errorf("parseCategories(%q) failed: %v", tc.in, err)
+		} else if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("parseCategories(%q) = %q; want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestShowAudit(t *testing.T) {
+	passed := audit{ID: "document-title", Title: "Document has a `<title>` element", Score: 100}
+	failed := audit{ID: "unused-javascript", Title: "Reduce unused JavaScript", Score: 40}
+	for _, tc := range []struct {
+		aud  *audit
+		cfg  reportConfig
+		want bool
+	}{
+		{&failed, reportConfig{audits: auditsFailed}, true},
+		{&passed, reportConfig{audits: auditsFailed}, false},
+		{&passed, reportConfig{audits: auditsAll}, true},
+		{&failed, reportConfig{audits: auditsNone}, false},
+		{&failed, reportConfig{audits: auditsAll, auditFilter: []string{"unused-javascript"}}, true},
+		{&failed, reportConfig{audits: auditsAll, auditFilter: []string{"unused javascript"}}, true},
+		{&passed, reportConfig{audits: auditsAll, auditFilter: []string{"unused javascript"}}, false},
+		{&failed, reportConfig{audits: auditsAll, auditFilter: []string{"unused"}, auditExclude: []string{"JavaScript"}}, false},
+		{&passed, reportConfig{audits: auditsAll, auditExclude: []string{"unused-javascript"}}, true},
+	} {
+		if got := showAudit(tc.aud, &tc.cfg); got != tc.want {
+			t.Errorf("showAudit(%q, filter=%q exclude=%q) = %v; want %v",
+				tc.aud.ID, tc.cfg.auditFilter, tc.cfg.auditExclude, got, tc.want)
+		}
+	}
+}
+
+func TestSortByScore(t *testing.T) {
+	for _, tc := range []struct {
+		sort string
+		want []string
+	}{
+		{"perf", []string{"/c", "/a", "/d", "/b", "/failed", "/noscore"}},
+		{"-perf", []string{"/b", "/a", "/d", "/c", "/failed", "/noscore"}},
+		{"-performance", []string{"/b", "/a", "/d", "/c", "/failed", "/noscore"}},
+	} {
+		reps := []*report{
+			{URL: "https://example.org/failed"},
+			newTestReport("https://example.org/a", map[string]int{"performance": 70}),
+			newTestReport("https://example.org/b", map[string]int{"performance": 90}),
+			newTestReport("https://example.org/noscore", map[string]int{"performance": -1}),
+			newTestReport("https://example.org/c", map[string]int{"performance": 40}),
+			newTestReport("https://example.org/d", map[string]int{"performance": 70}),
+		}
+		id, desc, ok := parseScoreSort(tc.sort)
+		if !ok {
+			t.Errorf("parseScoreSort(%q) failed", tc.sort)
+			continue
+		}
+		sortByScore(reps, id, desc)
+		var got []string
+		for _, rep := range reps {
+			got = append(got, urlPath(rep.URL))
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Sorting by %q produced %q; want %q", tc.sort, got, tc.want)
+		}
+	}
+
+	for _, s := range []string{"url", "delta", "Perf", "--perf", ""} {
+		if _, _, ok := parseScoreSort(s); ok {
+			t.Errorf("parseScoreSort(%q) unexpectedly succeeded", s)
+		}
+	}
+}
+
+func TestReadReport_NoPWA(t *testing.T) {
+	// Lighthouse 12 removed the PWA category, so it's absent even if requested.
+	res := &pso.PagespeedApiPagespeedResponseV5{
+		Id: "https://example.org/",
+		LighthouseResult: &pso.LighthouseResultV5{
+			LighthouseVersion: "12.0.0",
+			Categories: &pso.Categories{
+				Performance: &pso.LighthouseCategoryV5{
+					Id:        "performance",
+					Title:     "Performance",
+					Score:     0.87,
+					AuditRefs: []*pso.AuditRefs{{Id: "speed-index"}},
+				},
+				Seo: &pso.LighthouseCategoryV5{
+					Id:        "seo",
+					Title:     "SEO",
+					Score:     1.0,
+					AuditRefs: []*pso.AuditRefs{{Id: "document-title"}},
+				},
+			},
+			Audits: map[string]pso.LighthouseAuditResultV5{
+				"speed-index": {Id: "speed-index", Title: "Speed Index", Score: 0.5,
+					NumericValue: 4321.5, NumericUnit: "millisecond"},
+				"document-title": {Id: "document-title", Title: "Document has a title", Score: 1.0},
+			},
+		},
+	}
+	rep, err := readReport(res, &reportConfig{})
+	if err != nil {
+		t.Fatal("readReport failed: ", err)
+	}
+	want := &report{
+		URL: "https://example.org/",
+		Categories: []category{
+			{ID: "performance", Title: "Performance", Abbrev: "Perf", Score: 87, ScoreFloat: 0.87, Audits: []audit{
+				{ID: "speed-index", Title: "Speed Index", Score: 50, ScoreFloat: 0.5},
+			}},
+			{ID: "seo", Title: "SEO", Abbrev: "SEO", Score: 100, ScoreFloat: 1, Audits: []audit{
+				{ID: "document-title", Title: "Document has a title", Score: 100, ScoreFloat: 1},
+			}},
+		},
+		Metrics: map[string]float64{"si": 4321.5},
+	}
+	if !reflect.DeepEqual(rep, want) {
+		t.Errorf("readReport() = %+v; want %+v", rep, want)
+	}
+
+	// Requiring the PWA category shouldn't make the report incomplete.
+	checker := newCompletenessChecker([]string{"performance", "seo", "pwa"})
+	if err := checker.check(rep); err != nil {
+		t.Error("check failed: ", err)
+	}
+
+	// Categories that weren't requested via -categories should be skipped.
+	if rep, err := readReport(res, &reportConfig{categories: []string{"seo"}}); err != nil {
+		t.Error("readReport with -categories failed: ", err)
+	} else if !reflect.DeepEqual(rep.Categories, want.Categories[1:]) {
+		t.Errorf("readReport with -categories returned %+v; want %+v", rep.Categories, want.Categories[1:])
+	}
+}
+
+func TestReportJSON(t *testing.T) {
+	reps := []*report{
+		{URL: "https://example.org/a", Categories: []category{{ID: "seo", Title: "SEO", Abbrev: "SEO", Score: 100, ScoreFloat: 1,
+			Audits: []audit{{ID: "document-title", Title: "Document has a title", Score: 100, ScoreFloat: 1}}}}},
+		{URL: "https://example.org/b", Error: "timed out", ErrorReason: failureTimeout},
+	}
+	b, err := json.Marshal(reps)
+	if err != nil {
+		t.Fatal("Marshal failed: ", err)
+	}
+	want := `[{"url":"https://example.org/a","categories":[{"id":"seo","title":"SEO","abbrev":"SEO","score":100,"scoreFloat":1,` +
+		`"audits":[{"id":"document-title","title":"Document has a title","score":100,"scoreFloat":1}]}]},` +
+		`{"url":"https://example.org/b","categories":null,"error":"timed out","errorReason":"timeout"}]`
+	if got := string(b); got != want {
+		t.Errorf("Marshal(%+v) = %s; want %s", reps, got, want)
+	}
+
+	// Files written before fields had JSON tags should still be readable.
+	var old []*report
+	if err := json.Unmarshal([]byte(`[{"URL":"https://example.org/a","Categories":[{"ID":"seo","Score":90}]}]`), &old); err != nil {
+		t.Fatal("Unmarshal failed: ", err)
+	} else if len(old) != 1 || old[0].URL != "https://example.org/a" || len(old[0].Categories) != 1 ||
+		old[0].Categories[0].Score != 90 {
+		t.Errorf("Unmarshal of untagged JSON gave %+v", old[0])
+	}
+}
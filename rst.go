@@ -0,0 +1,133 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// rstEscaper escapes characters that have special meaning in reStructuredText inline markup.
+var rstEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"`", "\\`",
+	"_", `\_`,
+	"|", `\|`,
+)
+
+// escapeRST escapes s for use in reStructuredText.
+func escapeRST(s string) string { return rstEscaper.Replace(s) }
+
+// writeRST writes reps to w as a reStructuredText document containing
+// a summary table followed by each report's category scores and audits.
+func writeRST(w io.Writer, reps []*report, cfg *reportConfig) error {
+	if cfg.title != "" {
+		writeRSTHeading(w, escapeRST(cfg.title), "=")
+	}
+
+	cats := summaryCategories(reps)
+	rows := [][]string{{"URL"}}
+	for _, cat := range cats {
+		rows[0] = append(rows[0], cat.Abbrev)
+	}
+	for _, rep := range reps {
+		row := []string{escapeRST(displayURL(rep.URL, cfg))}
+		for _, cat := range cats {
+			var val string
+			if c := findCategory(rep, cat.ID); c != nil {
+				val = formatScore(c.Score, c.ScoreFloat, cfg)
+			}
+			row = append(row, val)
+		}
+		rows = append(rows, row)
+	}
+	writeRSTTable(w, rows)
+	fmt.Fprintln(w)
+
+	for _, rep := range reps {
+		writeRSTHeading(w, escapeRST(stripUserinfo(rep.URL)), "-")
+		if len(rep.Categories) == 0 {
+			fmt.Fprintln(w, "Report could not be fetched.")
+			fmt.Fprintln(w)
+			continue
+		}
+		for _, cat := range rep.Categories {
+			// Each category is a definition list item containing a bullet list of audits.
+			fmt.Fprintf(w, "%s: %s\n", escapeRST(cat.Title), formatScore(cat.Score, cat.ScoreFloat, cfg))
+			var n int
+			if cfg.audits != auditsNone {
+				for _, aud := range cat.Audits {
+					if !showAudit(&aud, cfg) {
+						continue
+					}
+					score := "."
+					if aud.Score >= 0 {
+						score = formatScore(aud.Score, aud.ScoreFloat, cfg)
+					}
+					ln := score + " " + escapeRST(aud.Title)
+					if aud.Value != "" {
+						ln += ": " + escapeRST(aud.Value)
+					}
+					fmt.Fprintf(w, "    - %s\n", ln)
+					n++
+				}
+			}
+			if n == 0 {
+				fmt.Fprintln(w, "    No audits listed.")
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	return nil
+}
+
+// writeRSTHeading writes a section title underlined using the supplied character.
+func writeRSTHeading(w io.Writer, title, ch string) {
+	fmt.Fprintln(w, title)
+	fmt.Fprintln(w, strings.Repeat(ch, utf8.RuneCountInString(title)))
+	fmt.Fprintln(w)
+}
+
+// writeRSTTable writes rows to w as a reStructuredText simple table.
+// The first row is used as the table's header.
+func writeRSTTable(w io.Writer, rows [][]string) {
+	var widths []int
+	for _, row := range rows {
+		for j, val := range row {
+			if j >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := utf8.RuneCountInString(val); n > widths[j] {
+				widths[j] = n
+			}
+		}
+	}
+	border := make([]string, len(widths))
+	for j, n := range widths {
+		border[j] = strings.Repeat("=", n)
+	}
+	writeRow := func(row []string) {
+		vals := make([]string, len(widths))
+		for j := range widths {
+			if j < len(row) {
+				vals[j] = row[j]
+			}
+			if j < len(widths)-1 {
+				vals[j] += strings.Repeat(" ", widths[j]-utf8.RuneCountInString(vals[j]))
+			}
+		}
+		fmt.Fprintln(w, strings.TrimRight(strings.Join(vals, "  "), " "))
+	}
+	writeRow(border)
+	for i, row := range rows {
+		writeRow(row)
+		if i == 0 {
+			writeRow(border)
+		}
+	}
+	writeRow(border)
+}
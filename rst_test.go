@@ -0,0 +1,67 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEscapeRST(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"Plain text", "Plain text"},
+		{"Avoid `document.write()`", "Avoid \\`document.write()\\`"},
+		{"/foo_bar/*|x", `/foo\_bar/\*\|x`},
+		{`a\b`, `a\\b`},
+	} {
+		if got := escapeRST(tc.in); got != tc.want {
+			t.Errorf("escapeRST(%q) = %q; want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestWriteRST(t *testing.T) {
+	reps := []*report{
+		{URL: "https://example.org/a_b", Categories: []category{
+			{ID: "performance", Title: "Performance", Abbrev: "Perf", Score: 85, ScoreFloat: -1, Audits: []audit{
+				{ID: "lcp", Title: "Largest Contentful Paint", Score: 45, ScoreFloat: -1, Value: "3.1 s"},
+				{ID: "fcp", Title: "First Contentful Paint", Score: 100, ScoreFloat: -1},
+			}},
+			{ID: "seo", Title: "SEO", Abbrev: "SEO", Score: 100, ScoreFloat: -1},
+		}},
+		{URL: "https://example.org/c"}, // failed
+	}
+	var b bytes.Buffer
+	if err := writeRST(&b, reps, &reportConfig{audits: auditsFailed}); err != nil {
+		t.Fatal("writeRST failed: ", err)
+	}
+	want := strings.Join([]string{
+		"=====  ====  ===",
+		"URL    Perf  SEO",
+		"=====  ====  ===",
+		`/a\_b  85    100`,
+		"/c",
+		"=====  ====  ===",
+		"",
+		`https://example.org/a\_b`,
+		"------------------------",
+		"",
+		"Performance: 85",
+		"    - 45 Largest Contentful Paint: 3.1 s",
+		"",
+		"SEO: 100",
+		"    No audits listed.",
+		"",
+		"https://example.org/c",
+		"---------------------",
+		"",
+		"Report could not be fetched.",
+		"",
+		"",
+	}, "\n")
+	if got := b.String(); got != want {
+		t.Errorf("writeRST wrote:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+	htransport "google.golang.org/api/transport/http"
+)
+
+// sheetHeader contains the column names written to the first row of an empty sheet.
+var sheetHeader = []interface{}{"Time", "URL", "Strategy", "Category", "Score", "Label"}
+
+// appendToSheet appends a row for each category in reps to the named sheet
+// (e.g. "Sheet1") within the Google Sheets spreadsheet with the supplied ID.
+// credsPath contains a service account's JSON credentials. Requests are
+// authorized on top of client's transport so that its retries are used.
+func appendToSheet(ctx context.Context, client *http.Client, reps []*report, cfg *reportConfig,
+	spreadsheetID, sheet, credsPath string) error {
+	tr, err := htransport.NewTransport(ctx, client.Transport,
+		option.WithCredentialsFile(credsPath), option.WithScopes(sheets.SpreadsheetsScope))
+	if err != nil {
+		return err
+	}
+	svc, err := sheets.NewService(ctx,
+		option.WithHTTPClient(&http.Client{Transport: tr, Timeout: client.Timeout}))
+	if err != nil {
+		return err
+	}
+
+	// Add a header row if the sheet is empty.
+	res, err := svc.Spreadsheets.Values.Get(spreadsheetID, sheet+"!A1:A1").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	rows := sheetRows(reps, cfg, len(res.Values) == 0)
+	if len(rows) == 0 {
+		return nil
+	}
+
+	// Append all of the rows in a single call.
+	_, err = svc.Spreadsheets.Values.Append(spreadsheetID, sheet, &sheets.ValueRange{Values: rows}).
+		ValueInputOption("RAW").
+		InsertDataOption("INSERT_ROWS").
+		Context(ctx).
+		Do()
+	return err
+}
+
+// sheetRows returns the rows that appendToSheet should write for reps,
+// preceded by sheetHeader if header is true. Failed reports are skipped.
+func sheetRows(reps []*report, cfg *reportConfig, header bool) [][]interface{} {
+	var rows [][]interface{}
+	for _, rep := range reps {
+		for _, cat := range rep.Categories {
+			rows = append(rows, []interface{}{
+				cfg.startTime.UTC().Format(time.RFC3339),
+				stripUserinfo(rep.URL),
+				strategy(cfg),
+				cat.ID,
+				cat.Score,
+				cfg.label,
+			})
+		}
+	}
+	if header && len(rows) > 0 {
+		rows = append([][]interface{}{sheetHeader}, rows...)
+	}
+	return rows
+}
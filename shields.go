@@ -0,0 +1,44 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"io"
+	"strconv"
+)
+
+// shieldsEndpoint is the JSON object expected by shields.io's endpoint badge.
+// See https://shields.io/endpoint.
+type shieldsEndpoint struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+	IsError       bool   `json:"isError,omitempty"`
+}
+
+// shieldsColors maps from scoreBand return values to shields.io colors.
+var shieldsColors = map[string]string{
+	"pass":    "green",
+	"average": "orange",
+	"fail":    "red",
+}
+
+// writeShields writes a shields.io endpoint JSON object to w describing
+// the score of the category with the supplied ID in rep.
+func writeShields(w io.Writer, rep *report, id string, cfg *reportConfig) error {
+	end := shieldsEndpoint{
+		SchemaVersion: 1,
+		Label:         id,
+		Message:       "error",
+		Color:         "lightgrey",
+		IsError:       true,
+	}
+	if cat := findCategory(rep, id); cat != nil && cat.Score >= 0 {
+		end.Message = strconv.Itoa(cat.Score)
+		end.Color = shieldsColors[scoreBand(cat.Score)]
+		end.IsError = false
+	}
+	return newJSONEncoder(w, cfg).Encode(&end)
+}
@@ -0,0 +1,58 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteShields(t *testing.T) {
+	for _, tc := range []struct {
+		rep  *report
+		want string
+	}{
+		{
+			&report{Categories: []category{{ID: "performance", Title: "Performance", Score: 85}}},
+			`{"schemaVersion":1,"label":"performance","message":"85","color":"orange"}`,
+		},
+		{
+			&report{Categories: []category{{ID: "performance", Title: "Performance", Score: 95}}},
+			`{"schemaVersion":1,"label":"performance","message":"95","color":"green"}`,
+		},
+		{
+			&report{Categories: []category{{ID: "performance", Title: "Performance", Score: 12}}},
+			`{"schemaVersion":1,"label":"performance","message":"12","color":"red"}`,
+		},
+		{
+			&report{Error: "failed"},
+			`{"schemaVersion":1,"label":"performance","message":"error","color":"lightgrey","isError":true}`,
+		},
+	} {
+		var b bytes.Buffer
+		if err := writeShields(&b, tc.rep, "performance", &reportConfig{}); err != nil {
+			t.Errorf("writeShields(%+v) failed: %v", tc.rep, err)
+		} else if got := b.String(); got != tc.want+"\n" {
+			t.Errorf("writeShields(%+v) = %q; want %q", tc.rep, got, tc.want+"\n")
+		}
+	}
+}
+
+func TestWriteShields_Pretty(t *testing.T) {
+	rep := &report{Categories: []category{{ID: "seo", Title: "SEO", Score: 100}}}
+	var b bytes.Buffer
+	if err := writeShields(&b, rep, "seo", &reportConfig{pretty: true}); err != nil {
+		t.Fatal("writeShields failed: ", err)
+	}
+	want := `{
+  "schemaVersion": 1,
+  "label": "seo",
+  "message": "100",
+  "color": "green"
+}
+`
+	if got := b.String(); got != want {
+		t.Errorf("writeShields wrote %q; want %q", got, want)
+	}
+}
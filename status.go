@@ -0,0 +1,64 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// urlStatus describes the outcome of checking a single URL.
+// A JSON array of urlStatus objects is written by -status-out.
+type urlStatus struct {
+	URL     string        `json:"url"`
+	Variant string        `json:"variant,omitempty"` // from -variant
+	Error   string        `json:"error,omitempty"`   // empty if the report was fetched
+	Reason  failureReason `json:"reason,omitempty"`
+}
+
+// writeStatus writes the status of each of reps to a JSON file at p.
+func writeStatus(p string, reps []*report, cfg *reportConfig) error {
+	sts := make([]urlStatus, len(reps))
+	for i, rep := range reps {
+		sts[i] = urlStatus{URL: rep.URL, Variant: rep.Variant, Error: rep.Error, Reason: rep.ErrorReason}
+	}
+	return writeFile(p, func(w io.Writer) error { return newJSONEncoder(w, cfg).Encode(sts) })
+}
+
+// readFailedURLs reads the status file at p (as written by writeStatus) and returns
+// the URLs that couldn't be fetched, along with the variant names of any variant URLs.
+// Since field names are matched case-insensitively, files written by -baseline can also be read.
+func readFailedURLs(p string) (urls []string, variants map[string]string, err error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	var sts []urlStatus
+	if err := json.Unmarshal(b, &sts); err != nil {
+		return nil, nil, fmt.Errorf("%v isn't a JSON array of URL statuses: %v", p, err)
+	}
+	variants = make(map[string]string)
+	for i, st := range sts {
+		if st.URL == "" {
+			return nil, nil, fmt.Errorf("%v: entry %d is missing URL", p, i)
+		}
+		if st.Error == "" {
+			continue
+		}
+		if hasString(urls, st.URL) {
+			continue
+		}
+		urls = append(urls, st.URL)
+		if st.Variant != "" {
+			variants[st.URL] = st.Variant
+		}
+	}
+	if len(sts) == 0 {
+		return nil, nil, errors.New("no URLs in " + p)
+	}
+	return urls, variants, nil
+}
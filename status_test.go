@@ -0,0 +1,65 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteStatus_ReadFailedURLs(t *testing.T) {
+	reps := []*report{
+		{URL: "https://example.org/a", Categories: []category{{ID: "seo", Score: 100}}},
+		{URL: "https://example.org/b", Error: "timed out", ErrorReason: failureTimeout},
+		{URL: "https://example.org/b?consent=1", Variant: "consent", Error: "bad", ErrorReason: failureOther},
+	}
+	p := filepath.Join(t.TempDir(), "status.json")
+	if err := writeStatus(p, reps, &reportConfig{}); err != nil {
+		t.Fatal("writeStatus failed: ", err)
+	}
+	urls, variants, err := readFailedURLs(p)
+	if err != nil {
+		t.Fatal("readFailedURLs failed: ", err)
+	}
+	if want := []string{"https://example.org/b", "https://example.org/b?consent=1"}; !reflect.DeepEqual(urls, want) {
+		t.Errorf("readFailedURLs returned URLs %q; want %q", urls, want)
+	}
+	if want := map[string]string{"https://example.org/b?consent=1": "consent"}; !reflect.DeepEqual(variants, want) {
+		t.Errorf("readFailedURLs returned variants %q; want %q", variants, want)
+	}
+}
+
+func TestReadFailedURLs_Baseline(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "baseline.json")
+	reps := []*report{
+		{URL: "https://example.org/a"},
+		{URL: "https://example.org/b", Error: "bad"},
+		{URL: "https://example.org/b", Error: "bad"},
+	}
+	if err := writeBaseline(p, reps); err != nil {
+		t.Fatal("writeBaseline failed: ", err)
+	}
+	urls, _, err := readFailedURLs(p)
+	if err != nil {
+		t.Fatal("readFailedURLs failed: ", err)
+	}
+	if want := []string{"https://example.org/b"}; !reflect.DeepEqual(urls, want) {
+		t.Errorf("readFailedURLs returned URLs %q; want %q", urls, want)
+	}
+}
+
+func TestReadFailedURLs_Malformed(t *testing.T) {
+	dir := t.TempDir()
+	for _, data := range []string{``, `{"url":"https://example.org/"}`, `[]`, `[{"error":"bad"}]`} {
+		p := filepath.Join(dir, "status.json")
+		if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if urls, _, err := readFailedURLs(p); err == nil {
+			t.Errorf("readFailedURLs(%q) = %q; want error", data, urls)
+		}
+	}
+}
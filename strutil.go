@@ -4,8 +4,13 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"net/url"
 	"regexp"
+	"strings"
+	"time"
 	"unicode/utf8"
 )
 
@@ -36,6 +41,14 @@ func elide(s string, max int) string {
 // Extracts the '[scheme]://[authority]/' part and remainder of a URL.
 var elideURLRegexp = regexp.MustCompile(`^([^/]+://[^/]+/)(.+)$`)
 
+// ansiRegexp matches ANSI escape sequences used to set text attributes.
+var ansiRegexp = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// textWidth returns the number of runes in s, ignoring ANSI escape sequences.
+func textWidth(s string) int {
+	return utf8.RuneCountInString(ansiRegexp.ReplaceAllString(s, ""))
+}
+
 // urlPath returns just the path portion (including leading slash) of the supplied URL.
 func urlPath(full string) string {
 	url, err := url.Parse(full)
@@ -47,3 +60,140 @@ func urlPath(full string) string {
 	url.Host = ""
 	return url.String()
 }
+
+// hasUserinfo returns true if the supplied URL contains userinfo (e.g. "user:pass@").
+func hasUserinfo(full string) bool {
+	u, err := url.Parse(full)
+	return err == nil && u.User != nil
+}
+
+// stripUserinfo returns the supplied URL with its userinfo removed.
+func stripUserinfo(full string) string {
+	u, err := url.Parse(full)
+	if err != nil || u.User == nil {
+		return full
+	}
+	u.User = nil
+	return u.String()
+}
+
+// urlFilename returns a filename-safe version of the supplied URL with its scheme
+// and userinfo removed. Path separators and other potentially-unsafe characters are
+// replaced with underscores, and a short hash of the URL is appended so that URLs
+// like "/a/b" and "/a_b" don't map to the same name.
+func urlFilename(full string) string {
+	full = stripUserinfo(full)
+	sum := sha256.Sum256([]byte(full))
+	if i := strings.Index(full, "://"); i >= 0 {
+		full = full[i+3:]
+	}
+	s := unsafeFilenameRegexp.ReplaceAllString(full, "_")
+	return strings.Trim(s, "_.") + "-" + hex.EncodeToString(sum[:4])
+}
+
+// Matches sequences of characters that shouldn't appear in filenames.
+var unsafeFilenameRegexp = regexp.MustCompile(`[^-_.a-zA-Z0-9]+`)
+
+// setQueryParam returns full with the named query parameter set to val.
+// If val is empty, the parameter is removed instead. Other parameters keep their order.
+// full is returned unchanged if it can't be parsed.
+func setQueryParam(full, name, val string) string {
+	u, err := url.Parse(full)
+	if err != nil {
+		return full
+	}
+	var params []string
+	if u.RawQuery != "" {
+		for _, p := range strings.Split(u.RawQuery, "&") {
+			if p != name && !strings.HasPrefix(p, name+"=") {
+				params = append(params, p)
+			}
+		}
+	}
+	if val != "" {
+		params = append(params, url.QueryEscape(name)+"="+url.QueryEscape(val))
+	}
+	u.RawQuery = strings.Join(params, "&")
+	return u.String()
+}
+
+// shellQuote returns s quoted for use as a single word in a POSIX shell command.
+// s is returned unchanged if it doesn't need to be quoted.
+func shellQuote(s string) string {
+	if s != "" && !unsafeShellRegexp.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Matches characters that need to be quoted in shell commands.
+var unsafeShellRegexp = regexp.MustCompile(`[^-_.,:/=+@%a-zA-Z0-9]`)
+
+// humanizeDuration returns an approximate human-readable description of d,
+// e.g. "1 second", "5 minutes", or "2 days". d is rounded down to the largest
+// whole unit, and negative durations are treated as zero.
+func humanizeDuration(d time.Duration) string {
+	for _, u := range []struct {
+		d    time.Duration
+		name string
+	}{
+		{24 * time.Hour, "day"},
+		{time.Hour, "hour"},
+		{time.Minute, "minute"},
+	} {
+		if d >= u.d {
+			return pluralize(int(d/u.d), u.name)
+		}
+	}
+	if d < 0 {
+		d = 0
+	}
+	return pluralize(int(d/time.Second), "second")
+}
+
+// pluralize returns e.g. "1 minute" or "2 minutes".
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// formatBytes returns a human-readable description of n bytes, e.g. "512 B" or "1.5 MiB".
+func formatBytes(n float64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%.0f B", n)
+	}
+	for _, suffix := range []string{"KiB", "MiB"} {
+		n /= unit
+		if n < unit || suffix == "MiB" {
+			if n < 10 {
+				return strings.TrimSuffix(fmt.Sprintf("%.1f", n), ".0") + " " + suffix
+			}
+			return fmt.Sprintf("%.0f %s", n, suffix)
+		}
+	}
+	return "" // not reached
+}
+
+// hasString returns true if list contains s.
+func hasString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList splits a comma-separated list, trimming whitespace and dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			items = append(items, v)
+		}
+	}
+	return items
+}
@@ -3,20 +3,19 @@
 
 package main
 
-import (
-	"strings"
-	"unicode/utf8"
-)
+import "strings"
 
 type tableCfg struct {
 	spacing   int
+	separator string // if non-empty, used between columns instead of spacing (no padding if it has tabs)
 	rightCols map[int]struct{}
 }
 
 type tableOpt func(cfg *tableCfg)
 
-func tableSpacing(spaces int) tableOpt { return func(cfg *tableCfg) { cfg.spacing = spaces } }
-func tableRightCol(idx int) tableOpt   { return func(cfg *tableCfg) { cfg.rightCols[idx] = struct{}{} } }
+func tableSpacing(spaces int) tableOpt   { return func(cfg *tableCfg) { cfg.spacing = spaces } }
+func tableRightCol(idx int) tableOpt     { return func(cfg *tableCfg) { cfg.rightCols[idx] = struct{}{} } }
+func tableSeparator(sep string) tableOpt { return func(cfg *tableCfg) { cfg.separator = sep } }
 
 // formatTable formats the supplied rows as lines of aligned columns.
 // Rows can have different numbers of columns.
@@ -37,7 +36,7 @@ func formatTable(rows [][]string, opts ...tableOpt) []string {
 	widths := make([]int, len(rows[0]))
 	for _, row := range rows {
 		for j, val := range row {
-			if width := utf8.RuneCountInString(val); j >= len(widths) {
+			if width := textWidth(val); j >= len(widths) {
 				widths = append(widths, width)
 			} else if width > widths[j] {
 				widths[j] = width
@@ -45,6 +44,14 @@ func formatTable(rows [][]string, opts ...tableOpt) []string {
 		}
 	}
 
+	sep := strings.Repeat(" ", cfg.spacing)
+	if cfg.separator != "" {
+		sep = cfg.separator
+	}
+	// Tab-separated output is aligned by whatever displays it (or parsed as TSV),
+	// so padding would just add trailing or leading spaces to values.
+	noPad := strings.Contains(sep, "\t")
+
 	lines := make([]string, len(rows))
 	for i, row := range rows {
 		for j, val := range row {
@@ -52,7 +59,10 @@ func formatTable(rows [][]string, opts ...tableOpt) []string {
 			if width == 0 {
 				continue // skip completely-empty columns
 			}
-			pad := strings.Repeat(" ", width-utf8.RuneCountInString(val))
+			var pad string
+			if !noPad {
+				pad = strings.Repeat(" ", width-textWidth(val))
+			}
 			_, right := cfg.rightCols[j]
 			if right {
 				lines[i] += pad
@@ -62,9 +72,11 @@ func formatTable(rows [][]string, opts ...tableOpt) []string {
 				if !right {
 					lines[i] += pad
 				}
-				lines[i] += strings.Repeat(" ", cfg.spacing)
+				lines[i] += sep
 			}
 		}
+		// Drop padding left by empty trailing values.
+		lines[i] = strings.TrimRight(lines[i], " ")
 	}
 	return lines
 }
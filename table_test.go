@@ -40,6 +40,26 @@ func TestFormatTable(t *testing.T) {
 			[]tableOpt{tableSpacing(2)},
 			[]string{"has empty column", "second"},
 		},
+		{
+			[][]string{{"a", "b"}, {"ccc", ""}},
+			[]tableOpt{tableSpacing(2), tableRightCol(1)},
+			[]string{"a    b", "ccc"},
+		},
+		{
+			[][]string{{"ab", "foo", "1"}, {"c", "barber", "100"}},
+			[]tableOpt{tableSpacing(2), tableSeparator(" | "), tableRightCol(2)},
+			[]string{"ab | foo    |   1", "c  | barber | 100"},
+		},
+		{
+			[][]string{{"ab", "foo"}, {"c", "barber"}},
+			[]tableOpt{tableSeparator("\t")},
+			[]string{"ab\tfoo", "c\tbarber"},
+		},
+		{
+			[][]string{{"URL", "Perf"}, {"/a", "9"}, {"/bcd", ""}},
+			[]tableOpt{tableSeparator("\t"), tableRightCol(1)},
+			[]string{"URL\tPerf", "/a\t9", "/bcd\t"},
+		},
 	} {
 		if got := formatTable(tc.in, tc.opts...); !reflect.DeepEqual(got, tc.want) {
 			t.Errorf("formatTable(%q, ...) = %q; want %q", tc.in, got, tc.want)
@@ -0,0 +1,45 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import "sort"
+
+// newTestReport returns a report for u with categories with the supplied scores,
+// keyed by category ID. Categories are ordered as in knownCategories and don't have
+// unrounded scores.
+func newTestReport(u string, scores map[string]int) *report {
+	rep := &report{URL: u}
+	for _, id := range knownCategories {
+		if score, ok := scores[id]; ok {
+			rep.Categories = append(rep.Categories, category{
+				ID:         id,
+				Abbrev:     categoryAbbrev(id),
+				Score:      score,
+				ScoreFloat: -1,
+			})
+		}
+	}
+	return rep
+}
+
+// newTestAuditReport returns a report for u with a "performance" category with the
+// supplied score and audits with the supplied scores, keyed by audit ID. Audits are
+// sorted by ID, titled e.g. "Audit a", and don't have unrounded scores.
+func newTestAuditReport(u string, score int, audits map[string]int) *report {
+	cat := category{
+		ID:         "performance",
+		Abbrev:     categoryAbbrev("performance"),
+		Score:      score,
+		ScoreFloat: -1,
+	}
+	ids := make([]string, 0, len(audits))
+	for id := range audits {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		cat.Audits = append(cat.Audits, audit{ID: id, Title: "Audit " + id, Score: audits[id], ScoreFloat: -1})
+	}
+	return &report{URL: u, Categories: []category{cat}}
+}
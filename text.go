@@ -4,8 +4,11 @@
 package main
 
 import (
+	"encoding/csv"
 	"fmt"
 	"io"
+	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -15,31 +18,245 @@ const (
 	catUnderlineLen  = 20 // length of '-' underlines below category names
 )
 
+// textTableOpts returns options for formatting tables in text output.
+func textTableOpts(cfg *reportConfig) []tableOpt {
+	opts := []tableOpt{tableSpacing(2)}
+	if cfg.tableSep != "" {
+		opts = append(opts, tableSeparator(cfg.tableSep))
+	}
+	return opts
+}
+
+// markScore appends cfg.failMarker to val (cat's formatted score)
+// if cat's score is below its minimum score.
+func markScore(val string, cat *category, cfg *reportConfig) string {
+	if cfg.failMarker == "" {
+		return val
+	}
+	if min, ok := minScore(cfg, cat.ID); ok && cat.Score < min {
+		return val + cfg.failMarker
+	}
+	return val
+}
+
+// displayURL returns u as it should be displayed in text output,
+// i.e. just its path unless cfg.fullURLs is set. Userinfo is never included.
+func displayURL(u string, cfg *reportConfig) string {
+	if cfg.fullURLs {
+		return stripUserinfo(u)
+	}
+	return urlPath(u)
+}
+
+// ansiColors maps from scoreBand's return values to ANSI escape sequences.
+var ansiColors = map[string]string{
+	"pass":    "\x1b[32m", // green
+	"average": "\x1b[33m", // yellow
+	"fail":    "\x1b[31m", // red
+}
+
+const ansiReset = "\x1b[0m"
+
+// colorScore wraps s in ANSI escape sequences if cfg.color is set, using the
+// color for score's Lighthouse band. s is returned unchanged for unset scores.
+func colorScore(s string, score int, cfg *reportConfig) string {
+	if !cfg.color || score < 0 {
+		return s
+	}
+	return ansiColors[scoreBand(score)] + s + ansiReset
+}
+
+// withoutColor returns a copy of cfg with cfg.color cleared, for writing output
+// that isn't displayed directly on a terminal.
+func withoutColor(cfg *reportConfig) *reportConfig {
+	c := *cfg
+	c.color = false
+	return &c
+}
+
 // writeSummary writes a text table to w summarizing the category scores
-// of each of the supplied reports.
+// of each of the supplied reports. The table is preceded by cfg.title if set.
 func writeSummary(w io.Writer, reps []*report, cfg *reportConfig) error {
-	// Add a heading row to the table, using categories from the first non-failed report.
+	if cfg.title != "" {
+		fmt.Fprintln(w, cfg.title)
+		fmt.Fprintln(w)
+	}
+	if cfg.groupByHost {
+		if err := writeHostSummary(w, reps, cfg); err != nil {
+			return err
+		}
+	}
+
+	// Add a heading row to the table, using categories from all reports.
+	cats := summaryCategories(reps)
 	rows := [][]string{[]string{"URL"}}
-	tableOpts := []tableOpt{tableSpacing(2)}
+	tableOpts := textTableOpts(cfg)
+	for i, cat := range cats {
+		rows[0] = append(rows[0], cat.Abbrev)
+		tableOpts = append(tableOpts, tableRightCol(i+1))
+	}
+	checkScores := len(cfg.minScores) > 0
+	if checkScores {
+		rows[0] = append(rows[0], "Pass")
+	}
+
+	// If a baseline was supplied, show changes relative to it.
+	var base map[string]*report
+	if len(cfg.baseline) > 0 {
+		base = baselineReports(cfg)
+	}
+	summaryURL := func(full string) string {
+		u := displayURL(full, cfg)
+		if cfg.summaryURLWidth > 0 {
+			u = elide(u, cfg.summaryURLWidth)
+		}
+		return u
+	}
+
+	seen := make(map[string]struct{}, len(reps))
+	for _, rep := range reps {
+		seen[rep.URL] = struct{}{}
+		u := summaryURL(rep.URL)
+		if rep.Variant != "" {
+			u += " [" + rep.Variant + "]"
+		}
+		brep, inBase := base[rep.URL]
+		if base != nil && !inBase {
+			u += " (new)"
+		}
+		row := []string{u}
+		for _, cat := range cats {
+			var val string
+			if c := findCategory(rep, cat.ID); c != nil {
+				val = markScore(formatScore(c.Score, c.ScoreFloat, cfg), c, cfg)
+				if cfg.barWidth > 0 && c.Score >= 0 {
+					val = fmt.Sprintf("%s %3s", scoreBar(c.Score, cfg.barWidth), val)
+				}
+				val = colorScore(val, c.Score, cfg)
+				if inBase {
+					if bc := findCategory(brep, cat.ID); bc != nil && bc.Score >= 0 && c.Score >= 0 {
+						val += " " + formatDelta(bc.Score, c.Score)
+					}
+				}
+			}
+			row = append(row, val)
+		}
+		if checkScores {
+			switch {
+			case len(rep.Categories) == 0:
+				row = append(row, "ERR")
+			case reportPassed(rep, cfg):
+				row = append(row, "✓")
+			default:
+				row = append(row, "✗")
+			}
+		}
+		rows = append(rows, row)
+	}
+	for _, brep := range cfg.baseline {
+		if _, ok := seen[brep.URL]; !ok {
+			rows = append(rows, []string{summaryURL(brep.URL) + " (gone)"})
+		}
+	}
+	if cfg.aggregate {
+		rows = append(rows, aggregateRows(reps, cats)...)
+	}
+	if cfg.transpose {
+		rows, tableOpts = transposeSummary(rows, cfg)
+	}
+	for _, ln := range formatTable(rows, tableOpts...) {
+		fmt.Fprintln(w, ln)
+	}
+	return nil
+}
+
+// aggregateRows returns summary table rows containing the mean, minimum, and
+// maximum score of each of cats across the non-failed reports in reps.
+func aggregateRows(reps []*report, cats []category) [][]string {
+	var n int
 	for _, rep := range reps {
 		if len(rep.Categories) > 0 {
-			for i, cat := range rep.Categories {
-				rows[0] = append(rows[0], cat.Abbrev)
-				tableOpts = append(tableOpts, tableRightCol(i+1))
+			n++
+		}
+	}
+	mean := []string{"Mean of " + pluralize(n, "URL")}
+	min := []string{"Min"}
+	max := []string{"Max"}
+	for _, cat := range cats {
+		var mv, lv, hv string
+		if m, cnt := meanScore(reps, cat.ID); cnt > 0 {
+			mv = strconv.Itoa(m)
+		}
+		if l, h, cnt := scoreRange(reps, cat.ID); cnt > 0 {
+			lv, hv = strconv.Itoa(l), strconv.Itoa(h)
+		}
+		mean, min, max = append(mean, mv), append(min, lv), append(max, hv)
+	}
+	return [][]string{mean, min, max}
+}
+
+// barEighths contains block characters filled to 1/8 through 7/8 of their width.
+var barEighths = []rune("▏▎▍▌▋▊▉")
+
+// scoreBar returns a bar of block characters width runes wide and filled
+// in proportion to score, in [0, 100].
+func scoreBar(score, width int) string {
+	eighths := (score*width*8 + 50) / 100
+	bar := strings.Repeat("█", eighths/8)
+	n := eighths / 8
+	if rem := eighths % 8; rem > 0 {
+		bar += string(barEighths[rem-1])
+		n++
+	}
+	return bar + strings.Repeat(" ", width-n)
+}
+
+// transposedURLWidth is the maximum width of URL headings in transposed summaries
+// if cfg.summaryURLWidth is unset.
+const transposedURLWidth = 20
+
+// transposeSummary transposes the summary table in rows so that each URL is in its own
+// column, returning the new rows and the options to use when formatting them.
+func transposeSummary(rows [][]string, cfg *reportConfig) ([][]string, []tableOpt) {
+	trows := make([][]string, len(rows[0]))
+	opts := textTableOpts(cfg)
+	for i, row := range rows {
+		for j, val := range row {
+			if j == 0 && i > 0 && cfg.summaryURLWidth <= 0 {
+				val = elide(val, transposedURLWidth)
 			}
-			break
+			trows[j] = append(trows[j], val)
+		}
+		if i > 0 {
+			opts = append(opts, tableRightCol(i))
 		}
 	}
+	return trows, opts
+}
+
+// summaryMetrics lists the names of the lab metrics included by writeMetricsSummary.
+var summaryMetrics = []string{"lcp", "cls", "tbt"}
 
+// writeMetricsSummary writes a text table to w containing key lab metrics
+// for each of the supplied reports.
+func writeMetricsSummary(w io.Writer, reps []*report, cfg *reportConfig) error {
+	rows := [][]string{{"URL"}}
+	tableOpts := textTableOpts(cfg)
+	for i, name := range summaryMetrics {
+		rows[0] = append(rows[0], strings.ToUpper(name))
+		tableOpts = append(tableOpts, tableRightCol(i+1))
+	}
 	for _, rep := range reps {
-		var row []string
-		if cfg.fullURLs {
-			row = append(row, rep.URL)
-		} else {
-			row = append(row, urlPath(rep.URL))
+		row := []string{displayURL(rep.URL, cfg)}
+		if cfg.summaryURLWidth > 0 {
+			row[0] = elide(row[0], cfg.summaryURLWidth)
 		}
-		for _, cat := range rep.Categories {
-			row = append(row, strconv.Itoa(cat.Score))
+		for _, name := range summaryMetrics {
+			var val string
+			if v, ok := rep.Metrics[name]; ok {
+				val = formatMetric(name, v)
+			}
+			row = append(row, val)
 		}
 		rows = append(rows, row)
 	}
@@ -49,12 +266,217 @@ func writeSummary(w io.Writer, reps []*report, cfg *reportConfig) error {
 	return nil
 }
 
+// writeHostSummary writes a text table to w containing the mean category scores
+// of the supplied reports grouped by hostname. Nothing is written if all of
+// the reports are from the same host.
+func writeHostSummary(w io.Writer, reps []*report, cfg *reportConfig) error {
+	var hosts []string
+	groups := make(map[string][]*report)
+	for _, rep := range reps {
+		var host string
+		if u, err := url.Parse(rep.URL); err == nil {
+			host = u.Hostname()
+		}
+		if _, ok := groups[host]; !ok {
+			hosts = append(hosts, host)
+		}
+		groups[host] = append(groups[host], rep)
+	}
+	if len(hosts) < 2 {
+		return nil
+	}
+
+	cats := summaryCategories(reps)
+	rows := [][]string{[]string{"Host", "URLs"}}
+	tableOpts := append(textTableOpts(cfg), tableRightCol(1))
+	for i, cat := range cats {
+		rows[0] = append(rows[0], cat.Abbrev)
+		tableOpts = append(tableOpts, tableRightCol(i+2))
+	}
+	for _, host := range hosts {
+		row := []string{host, strconv.Itoa(len(groups[host]))}
+		for _, cat := range cats {
+			var val string
+			if mean, n := meanScore(groups[host], cat.ID); n > 0 {
+				val = strconv.Itoa(mean)
+			}
+			row = append(row, val)
+		}
+		rows = append(rows, row)
+	}
+	for _, ln := range formatTable(rows, tableOpts...) {
+		fmt.Fprintln(w, ln)
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
+// writeStats writes a table to w listing the audits that failed for
+// the supplied reports, ordered by the number of URLs that failed each audit.
+// The number of API calls is also written if cfg.apiCalls is set.
+func writeStats(w io.Writer, reps []*report, cfg *reportConfig) error {
+	type stat struct {
+		id, title string
+		urls      []string
+	}
+	stats := make(map[string]*stat)
+	for _, rep := range reps {
+		u := displayURL(rep.URL, cfg)
+		seen := make(map[string]struct{}) // audits can appear in multiple categories
+		for _, cat := range rep.Categories {
+			for _, aud := range cat.Audits {
+				if _, ok := seen[aud.ID]; ok || !auditFailed(&aud) {
+					continue
+				}
+				seen[aud.ID] = struct{}{}
+				st := stats[aud.ID]
+				if st == nil {
+					st = &stat{id: aud.ID, title: aud.Title}
+					stats[aud.ID] = st
+				}
+				st.urls = append(st.urls, u)
+			}
+		}
+	}
+
+	sorted := make([]*stat, 0, len(stats))
+	for _, st := range stats {
+		sorted = append(sorted, st)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if ni, nj := len(sorted[i].urls), len(sorted[j].urls); ni != nj {
+			return ni > nj
+		}
+		return sorted[i].id < sorted[j].id
+	})
+
+	fmt.Fprintln(w, "Failed audits:")
+	fmt.Fprintln(w)
+	rows := [][]string{{"URLs", "Audit", "Affected"}}
+	for _, st := range sorted {
+		rows = append(rows, []string{
+			strconv.Itoa(len(st.urls)),
+			fmt.Sprintf("%s [%s]", st.title, st.id),
+			strings.Join(st.urls, " "),
+		})
+	}
+	for _, ln := range formatTable(rows, append(textTableOpts(cfg), tableRightCol(0))...) {
+		fmt.Fprintln(w, ln)
+	}
+	if cfg.apiCalls != nil {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, cfg.apiCalls)
+	}
+	return nil
+}
+
+// writeFilmstrip writes a table to w describing the supplied filmstrip frames.
+func writeFilmstrip(w io.Writer, frames []filmstripFrame, cfg *reportConfig) {
+	fmt.Fprintln(w, "Filmstrip")
+	fmt.Fprintln(w, strings.Repeat("-", catUnderlineLen))
+	var rows [][]string
+	for i, fr := range frames {
+		var desc string
+		switch {
+		case i == 0:
+			desc = "first frame"
+		case fr.Changed:
+			desc = "changed"
+		default:
+			desc = "unchanged"
+		}
+		rows = append(rows, []string{fmt.Sprintf("%.0f ms", fr.Timing), desc})
+	}
+	for _, ln := range formatTable(rows, append(textTableOpts(cfg), tableRightCol(0))...) {
+		fmt.Fprintln(w, ln)
+	}
+	fmt.Fprintln(w)
+}
+
+// formatSavings returns a description of aud's estimated savings, e.g. "1200 ms, 340 KiB",
+// or an empty string if there are no savings.
+func formatSavings(aud *audit) string {
+	var parts []string
+	if aud.SavingsMs > 0 {
+		parts = append(parts, fmt.Sprintf("%.0f ms", aud.SavingsMs))
+	}
+	if aud.SavingsBytes > 0 {
+		parts = append(parts, formatBytes(aud.SavingsBytes))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// writeTOC writes a numbered list of the reports that will be written by writeReports,
+// along with their category scores. URLs are written exactly as in report headers
+// so they can be searched for.
+func writeTOC(w io.Writer, reps []*report, cfg *reportConfig) {
+	fmt.Fprintln(w, "Contents:")
+	fmt.Fprintln(w)
+	var rows [][]string
+	var n int
+	for _, rep := range reps {
+		if skipReport(rep, cfg) {
+			continue
+		}
+		n++
+		rows = append(rows, []string{strconv.Itoa(n) + ".", stripUserinfo(rep.URL), tocScores(rep, cfg)})
+	}
+	for _, ln := range formatTable(rows, append(textTableOpts(cfg), tableRightCol(0))...) {
+		fmt.Fprintf(w, "  %s\n", ln)
+	}
+	fmt.Fprintln(w)
+}
+
+// tocScores returns a string like "Perf 80, SEO 100" listing rep's category
+// scores for a table of contents, or "failed" if rep couldn't be fetched.
+func tocScores(rep *report, cfg *reportConfig) string {
+	if len(rep.Categories) == 0 {
+		return "failed"
+	}
+	var scores []string
+	for _, cat := range rep.Categories {
+		scores = append(scores, cat.Abbrev+" "+formatScore(cat.Score, cat.ScoreFloat, cfg))
+	}
+	return strings.Join(scores, ", ")
+}
+
+// skipReport returns true if rep should be omitted from full reports, i.e. if it
+// couldn't be fetched and cfg.noFailedReports is set, or if it passed all minimum
+// scores and cfg.onlyFailedCats is set.
+func skipReport(rep *report, cfg *reportConfig) bool {
+	if len(rep.Categories) == 0 {
+		return cfg.noFailedReports
+	}
+	return cfg.onlyFailedCats && len(cfg.minScores) > 0 && reportPassed(rep, cfg)
+}
+
+// showCategory returns true if cat should be included in full reports according to
+// cfg.focusCat and cfg.onlyFailedCats.
+func showCategory(cat *category, cfg *reportConfig) bool {
+	if cfg.focusCat != "" && cat.ID != cfg.focusCat {
+		return false
+	}
+	if cfg.onlyFailedCats {
+		if min, ok := minScore(cfg, cat.ID); !ok || cat.Score >= min {
+			return false
+		}
+	}
+	return true
+}
+
 // writeReports calls writeReport, printing a divider line between each report.
+// Reports are skipped as described by skipReport.
 func writeReports(w io.Writer, reps []*report, cfg *reportConfig) error {
+	if cfg.toc {
+		writeTOC(w, reps, cfg)
+	}
 	for _, rep := range reps {
+		if skipReport(rep, cfg) {
+			continue
+		}
 		fmt.Fprint(w, strings.Repeat("=", reportDividerLen)+"\n\n")
 		if err := writeReport(w, rep, cfg); err != nil {
-			return fmt.Errorf("%v: %v", rep.URL, err)
+			return fmt.Errorf("%v: %v", stripUserinfo(rep.URL), err)
 		}
 	}
 	return nil
@@ -62,23 +484,55 @@ func writeReports(w io.Writer, reps []*report, cfg *reportConfig) error {
 
 // writeReport writes rep to w in text format.
 func writeReport(w io.Writer, rep *report, cfg *reportConfig) error {
-	fmt.Fprintln(w, rep.URL)
+	fmt.Fprintln(w, stripUserinfo(rep.URL))
 	fmt.Fprintln(w)
 
+	if tp := rep.ThirdParty; tp != nil {
+		fmt.Fprintf(w, "Third-party impact: %s, %.0f ms blocking\n", formatBytes(tp.TransferSize), tp.BlockingTime)
+		fmt.Fprintln(w)
+	}
+
+	if len(rep.Filmstrip) > 0 {
+		writeFilmstrip(w, rep.Filmstrip, cfg)
+	}
+
+	if cfg.fieldData && len(rep.Categories) > 0 {
+		writeFieldData(w, rep, cfg)
+	}
+
+	if cfg.metricsOnly && len(rep.Metrics) > 0 {
+		fmt.Fprintln(w, "Lab metrics")
+		fmt.Fprintln(w, strings.Repeat("-", catUnderlineLen))
+		var rows [][]string
+		for _, m := range labMetrics {
+			if v, ok := rep.Metrics[m.name]; ok {
+				rows = append(rows, []string{strings.ToUpper(m.name), formatMetric(m.name, v)})
+			}
+		}
+		for _, ln := range formatTable(rows, append(textTableOpts(cfg), tableRightCol(1))...) {
+			fmt.Fprintln(w, ln)
+		}
+		fmt.Fprintln(w)
+	}
+
 	for _, cat := range rep.Categories {
-		fmt.Fprintf(w, "%3d %s\n", cat.Score, cat.Title)
-		if cfg.audits == auditsNone {
+		if !showCategory(&cat, cfg) {
+			continue
+		}
+		score := fmt.Sprintf("%3s", markScore(formatScore(cat.Score, cat.ScoreFloat, cfg), &cat, cfg))
+		fmt.Fprintf(w, "%s %s\n", colorScore(score, cat.Score, cfg), cat.Title)
+		if cfg.audits == auditsNone || cfg.metricsOnly {
 			continue
 		}
 		fmt.Fprintln(w, strings.Repeat("-", catUnderlineLen))
 		for _, aud := range cat.Audits {
-			if cfg.audits == auditsFailed && (aud.Score < 0 || aud.Score == 100) {
+			if !showAudit(&aud, cfg) {
 				continue
 			}
 
 			var ln string
 			if aud.Score >= 0 {
-				ln += fmt.Sprintf("%3d", aud.Score)
+				ln += colorScore(fmt.Sprintf("%3s", formatScore(aud.Score, aud.ScoreFloat, cfg)), aud.Score, cfg)
 			} else {
 				ln += "  ."
 			}
@@ -86,28 +540,88 @@ func writeReport(w io.Writer, rep *report, cfg *reportConfig) error {
 			if aud.Value != "" {
 				ln += ": " + aud.Value
 			}
+			if s := formatSavings(&aud); s != "" {
+				ln += " (est. " + s + ")"
+			}
 			fmt.Fprintln(w, ln)
 
-			if len(aud.Details) > 0 && cfg.maxDetails != 0 {
-				// Elide long values.
-				if cfg.detailWidth > 0 {
-					for _, row := range aud.Details {
-						for j, val := range row {
-							row[j] = elide(val, cfg.detailWidth)
-						}
+			if cfg.maxDetails != 0 {
+				for _, table := range aud.Details {
+					for _, ln := range formatDetails(table, cfg) {
+						fmt.Fprintf(w, "    %s\n", ln)
 					}
 				}
-				details := formatTable(aud.Details, tableSpacing(2))
-				if cfg.maxDetails > 0 && len(details) > cfg.maxDetails {
-					details[cfg.maxDetails-1] = fmt.Sprintf("[%d more]", len(details)-cfg.maxDetails+1)
-					details = details[:cfg.maxDetails]
-				}
-				for _, det := range details {
-					fmt.Fprintf(w, "    %s\n", det)
-				}
 			}
 		}
 		fmt.Fprintln(w)
 	}
 	return nil
 }
+
+// formatDetails formats a table from audit.Details as lines of text, limiting
+// the table's rows, columns, and column widths as specified by cfg.
+func formatDetails(table [][]string, cfg *reportConfig) []string {
+	// Drop extra columns and elide long values. New rows are built so that the
+	// audit's details (which may be written as JSON later) aren't modified.
+	var dropped int
+	if cfg.maxDetailCols > 0 && len(table) > 0 && len(table[0]) > cfg.maxDetailCols {
+		dropped = len(table[0]) - cfg.maxDetailCols
+	}
+	rows := make([][]string, len(table))
+	for i, row := range table {
+		if dropped > 0 && len(row) > cfg.maxDetailCols {
+			row = row[:cfg.maxDetailCols]
+		}
+		rows[i] = make([]string, len(row))
+		for j, val := range row {
+			if cfg.detailWidth > 0 {
+				val = elide(val, cfg.detailWidth)
+			}
+			rows[i][j] = val
+		}
+	}
+	lines := formatTable(rows, textTableOpts(cfg)...)
+	if cfg.maxDetails > 0 && len(lines) > cfg.maxDetails {
+		lines[cfg.maxDetails-1] = fmt.Sprintf(cfg.moreFormat, len(lines)-cfg.maxDetails+1)
+		lines = lines[:cfg.maxDetails]
+	}
+	if dropped > 0 {
+		lines = append(lines, fmt.Sprintf("(+%d cols)", dropped))
+	}
+	return lines
+}
+
+// writeSummaryCSV writes a CSV version of the table written by writeSummary to w.
+// Failed reports have empty score cells. A "Label" column is added if cfg.label is set.
+func writeSummaryCSV(w io.Writer, reps []*report, cfg *reportConfig) error {
+	cats := summaryCategories(reps)
+	cw := csv.NewWriter(w)
+	row := []string{"URL"}
+	for _, cat := range cats {
+		row = append(row, cat.Abbrev)
+	}
+	if cfg.label != "" {
+		row = append(row, "Label")
+	}
+	if err := cw.Write(row); err != nil {
+		return err
+	}
+	for _, rep := range reps {
+		row := []string{stripUserinfo(rep.URL)}
+		for _, cat := range cats {
+			var val string
+			if c := findCategory(rep, cat.ID); c != nil {
+				val = formatScore(c.Score, c.ScoreFloat, cfg)
+			}
+			row = append(row, val)
+		}
+		if cfg.label != "" {
+			row = append(row, cfg.label)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,62 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseMinScores parses a -min-score flag value. The value consists of comma-separated
+// items that are either a bare score applying to all categories (e.g. "90") or a
+// category ID and score (e.g. "performance=90"). The returned map is keyed by category ID,
+// with the empty string holding the score for unlisted categories.
+func parseMinScores(s string) (map[string]int, error) {
+	scores := make(map[string]int)
+	if s == "" {
+		return scores, nil
+	}
+	for _, item := range strings.Split(s, ",") {
+		var id, val string
+		if i := strings.IndexByte(item, '='); i >= 0 {
+			id, val = strings.TrimSpace(item[:i]), strings.TrimSpace(item[i+1:])
+			if !knownCategory(id) {
+				return nil, fmt.Errorf("unknown category %q", id)
+			}
+		} else {
+			val = strings.TrimSpace(item)
+		}
+		score, err := strconv.Atoi(val)
+		if err != nil || score < 0 || score > 100 {
+			return nil, fmt.Errorf("bad score %q", val)
+		}
+		scores[id] = score
+	}
+	return scores, nil
+}
+
+// minScore returns the minimum acceptable score for the category with the supplied ID.
+// false is returned if the category has no minimum score.
+func minScore(cfg *reportConfig, id string) (int, bool) {
+	if score, ok := cfg.minScores[id]; ok {
+		return score, true
+	}
+	score, ok := cfg.minScores[""]
+	return score, ok
+}
+
+// reportPassed returns true if all of rep's categories meet their minimum scores.
+// Failed reports (i.e. ones without any categories) never pass.
+func reportPassed(rep *report, cfg *reportConfig) bool {
+	if len(rep.Categories) == 0 {
+		return false
+	}
+	for _, cat := range rep.Categories {
+		if min, ok := minScore(cfg, cat.ID); ok && cat.Score < min {
+			return false
+		}
+	}
+	return true
+}
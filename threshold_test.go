@@ -0,0 +1,62 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMinScores(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want map[string]int // nil if error expected
+	}{
+		{"", map[string]int{}},
+		{"90", map[string]int{"": 90}},
+		{"performance=90", map[string]int{"performance": 90}},
+		{"80, performance=90,seo=100", map[string]int{"": 80, "performance": 90, "seo": 100}},
+		{"perf=90", nil},
+		{"performance=", nil},
+		{"101", nil},
+		{"-1", nil},
+		{"abc", nil},
+	} {
+		got, err := parseMinScores(tc.in)
+		if tc.want == nil {
+			if err == nil {
+				t.Errorf("parseMinScores(%q) unexpectedly succeeded", tc.in)
+			}
+		} else if err != nil {
+			t.Errorf("parseMinScores(%q) failed: %v", tc.in, err)
+		} else if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("parseMinScores(%q) = %v; want %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestReportPassed(t *testing.T) {
+	rep := &report{Categories: []category{
+		{ID: "performance", Score: 85},
+		{ID: "seo", Score: 95},
+	}}
+	for _, tc := range []struct {
+		rep  *report
+		min  map[string]int
+		want bool
+	}{
+		{rep, map[string]int{}, true},
+		{rep, map[string]int{"": 85}, true},
+		{rep, map[string]int{"": 90}, false},
+		{rep, map[string]int{"": 90, "performance": 80}, true},
+		{rep, map[string]int{"seo": 96}, false},
+		{rep, map[string]int{"accessibility": 100}, true},
+		{&report{}, map[string]int{}, false},
+	} {
+		cfg := reportConfig{minScores: tc.min}
+		if got := reportPassed(tc.rep, &cfg); got != tc.want {
+			t.Errorf("reportPassed(%v, %v) = %v; want %v", tc.rep.Categories, tc.min, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	dialTimeout         = 30 * time.Second // timeout for establishing TCP connections
+	dialKeepAlive       = 30 * time.Second // interval between TCP keep-alive probes
+	tlsHandshakeTimeout = 10 * time.Second // timeout for TLS handshakes
+)
+
+// retryTransport is an http.RoundTripper that retries requests that fail due to
+// transient network errors (see transientNetError). Other errors (e.g. invalid
+// certificates) and responses with error status codes are returned unchanged.
+type retryTransport struct {
+	base    http.RoundTripper
+	retries int           // maximum retries after the initial attempt
+	delay   time.Duration // delay before the first retry (doubled for each subsequent retry)
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	delay := t.delay
+	for attempt := 0; ; attempt++ {
+		res, err := t.base.RoundTrip(req)
+		if err == nil || attempt >= t.retries || ctx.Err() != nil || !transientNetError(err) {
+			return res, err
+		}
+		// Requests with bodies can only be retried if the body can be recreated.
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return res, err
+			}
+			body, berr := req.GetBody()
+			if berr != nil {
+				return res, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+		delay *= 2
+	}
+}
+
+// transientNetError returns true if err, returned by an http.RoundTripper, was caused
+// by a timeout (including dial and TLS handshake timeouts), a reset connection, or a
+// temporary DNS failure.
+func transientNetError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsTemporary {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNABORTED)
+}
+
+// dnsCache caches the addresses returned by DNS lookups.
+type dnsCache struct {
+	resolver interface {
+		LookupHost(ctx context.Context, host string) ([]string, error)
+	}
+	dialer net.Dialer    // same settings as http.DefaultTransport's dialer, which dialContext replaces
+	ttl    time.Duration // how long to cache addresses
+	now    func() time.Time
+	dial   func(ctx context.Context, network, addr string) (net.Conn, error) // dials IP addresses
+
+	mu      sync.Mutex
+	entries map[string]dnsCacheEntry // keyed by hostname
+}
+
+type dnsCacheEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+func newDNSCache(ttl time.Duration) *dnsCache {
+	c := &dnsCache{
+		resolver: net.DefaultResolver,
+		dialer:   net.Dialer{Timeout: dialTimeout, KeepAlive: dialKeepAlive},
+		ttl:      ttl,
+		now:      time.Now,
+		entries:  make(map[string]dnsCacheEntry),
+	}
+	c.dial = c.dialer.DialContext
+	return c
+}
+
+// lookup returns the addresses for host, performing a DNS lookup if they aren't cached.
+func (c *dnsCache) lookup(ctx context.Context, host string) ([]string, error) {
+	c.mu.Lock()
+	ent, ok := c.entries[host]
+	c.mu.Unlock()
+	if ok && c.now().Before(ent.expires) {
+		return ent.addrs, nil
+	}
+
+	addrs, err := c.resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.entries[host] = dnsCacheEntry{addrs, c.now().Add(c.ttl)}
+	c.mu.Unlock()
+	return addrs, nil
+}
+
+// dialContext can be used as http.Transport.DialContext.
+// Like net.Dialer, it uses "Happy Eyeballs" (RFC 6555) to connect to addr's cached
+// addresses: addresses in the same family as the first one are tried in turn, and
+// addresses in the other family are tried in parallel after c.dialer.FallbackDelay
+// or after the first family's addresses have all failed.
+func (c *dnsCache) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return c.dial(ctx, network, addr)
+	}
+	addrs, err := c.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var primaries, fallbacks []string
+	for _, a := range addrs {
+		ip := net.ParseIP(a)
+		if ip == nil {
+			continue
+		}
+		v4 := ip.To4() != nil
+		if (network == "tcp4" && !v4) || (network == "tcp6" && v4) {
+			continue
+		}
+		if len(primaries) == 0 || (net.ParseIP(primaries[0]).To4() != nil) == v4 {
+			primaries = append(primaries, net.JoinHostPort(a, port))
+		} else {
+			fallbacks = append(fallbacks, net.JoinHostPort(a, port))
+		}
+	}
+	if len(primaries) == 0 {
+		return nil, &net.AddrError{Err: "no suitable address found", Addr: host}
+	}
+	if len(fallbacks) == 0 {
+		return c.dialSerial(ctx, network, primaries)
+	}
+	return c.dialParallel(ctx, network, primaries, fallbacks)
+}
+
+// dialSerial tries to connect to each of addrs in turn, returning the first connection.
+func (c *dnsCache) dialSerial(ctx context.Context, network string, addrs []string) (net.Conn, error) {
+	var err error
+	for _, a := range addrs {
+		var conn net.Conn
+		if conn, err = c.dial(ctx, network, a); err == nil {
+			return conn, nil
+		} else if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, err
+}
+
+// defaultFallbackDelay is used by dialParallel if c.dialer.FallbackDelay is unset.
+// It matches net.Dialer's default.
+const defaultFallbackDelay = 300 * time.Millisecond
+
+// dialParallel races dialSerial calls for primaries and fallbacks, starting the latter
+// after a delay. The first successful connection is returned and the other is closed.
+func (c *dnsCache) dialParallel(ctx context.Context, network string, primaries, fallbacks []string) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		conn    net.Conn
+		err     error
+		primary bool
+	}
+	results := make(chan result)
+	returned := make(chan struct{})
+	defer close(returned)
+	dial := func(addrs []string, primary bool) {
+		conn, err := c.dialSerial(ctx, network, addrs)
+		select {
+		case results <- result{conn, err, primary}:
+		case <-returned:
+			if conn != nil {
+				conn.Close()
+			}
+		}
+	}
+
+	delay := c.dialer.FallbackDelay
+	if delay <= 0 {
+		delay = defaultFallbackDelay
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	go dial(primaries, true)
+
+	var primaryErr, fallbackErr error
+	for {
+		select {
+		case <-timer.C:
+			go dial(fallbacks, false)
+		case res := <-results:
+			if res.err == nil {
+				return res.conn, nil
+			}
+			if res.primary {
+				primaryErr = res.err
+				// Start the fallbacks now if they haven't been started yet.
+				if timer.Stop() {
+					timer.Reset(0)
+				}
+			} else {
+				fallbackErr = res.err
+			}
+			if primaryErr != nil && fallbackErr != nil {
+				return nil, primaryErr
+			}
+		}
+	}
+}
+
+// newHTTPClient returns an HTTP client for a network phase (e.g. API calls).
+// Each request, including any retries after network errors, is limited to timeout
+// (no limit if zero), and up to retries retries are performed after transient
+// network errors. Connecting and TLS handshakes are limited to dialTimeout and
+// tlsHandshakeTimeout. DNS lookups are cached for dnsTTL (no caching if zero).
+func newHTTPClient(timeout time.Duration, retries int, dnsTTL time.Duration) *http.Client {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.TLSHandshakeTimeout = tlsHandshakeTimeout
+	if dnsTTL > 0 {
+		tr.DialContext = newDNSCache(dnsTTL).dialContext
+	} else {
+		tr.DialContext = (&net.Dialer{Timeout: dialTimeout, KeepAlive: dialKeepAlive}).DialContext
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &retryTransport{
+			base:    tr,
+			retries: retries,
+			delay:   500 * time.Millisecond,
+		},
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// errConnReset resembles the error returned when a server resets a connection.
+var errConnReset = &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+
+// roundTripFunc adapts a function to the http.RoundTripper interface.
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestRetryTransport(t *testing.T) {
+	for _, tc := range []struct {
+		failures int // initial attempts that should fail
+		retries  int
+		ok       bool // request should succeed
+		attempts int  // expected total attempts
+	}{
+		{0, 2, true, 1},
+		{1, 2, true, 2},
+		{2, 2, true, 3},
+		{3, 2, false, 3},
+		{1, 0, false, 1},
+	} {
+		var attempts int
+		tr := &retryTransport{
+			base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				attempts++
+				if attempts <= tc.failures {
+					return nil, errConnReset
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+			}),
+			retries: tc.retries,
+		}
+		req, _ := http.NewRequest(http.MethodGet, "https://example.org/", nil)
+		_, err := tr.RoundTrip(req)
+		if ok := err == nil; ok != tc.ok {
+			t.Errorf("%d failure(s), %d retries: got error %v", tc.failures, tc.retries, err)
+		}
+		if attempts != tc.attempts {
+			t.Errorf("%d failure(s), %d retries: made %d attempt(s); want %d",
+				tc.failures, tc.retries, attempts, tc.attempts)
+		}
+	}
+}
+
+func TestRetryTransport_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var attempts int
+	tr := &retryTransport{
+		base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			attempts++
+			cancel()
+			return nil, errConnReset
+		}),
+		retries: 5,
+	}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://example.org/", nil)
+	if _, err := tr.RoundTrip(req); err == nil {
+		t.Error("RoundTrip unexpectedly succeeded")
+	}
+	if attempts != 1 {
+		t.Errorf("RoundTrip made %d attempts after context was canceled; want 1", attempts)
+	}
+}
+
+func TestRetryTransport_Permanent(t *testing.T) {
+	var attempts int
+	tr := &retryTransport{
+		base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			attempts++
+			return nil, x509.UnknownAuthorityError{}
+		}),
+		retries: 5,
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://example.org/", nil)
+	if _, err := tr.RoundTrip(req); err == nil {
+		t.Error("RoundTrip unexpectedly succeeded")
+	}
+	if attempts != 1 {
+		t.Errorf("RoundTrip made %d attempts after certificate error; want 1", attempts)
+	}
+}
+
+func TestTransientNetError(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{errConnReset, true},
+		{fmt.Errorf("wrapped: %w", errConnReset), true},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, false},
+		{&net.DNSError{Err: "server misbehaving", Name: "example.org", IsTemporary: true}, true},
+		{&net.DNSError{Err: "i/o timeout", Name: "example.org", IsTimeout: true}, true},
+		{&net.DNSError{Err: "no such host", Name: "example.org", IsNotFound: true}, false},
+		{context.DeadlineExceeded, true},
+		{x509.UnknownAuthorityError{}, false},
+		{errors.New("tls: handshake failure"), false},
+	} {
+		if got := transientNetError(tc.err); got != tc.want {
+			t.Errorf("transientNetError(%q) = %v; want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+// fakeResolver counts lookups and returns fixed addresses.
+type fakeResolver struct {
+	lookups int
+	addrs   []string // "192.0.2.1" if empty
+}
+
+func (r *fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.lookups++
+	if len(r.addrs) > 0 {
+		return r.addrs, nil
+	}
+	return []string{"192.0.2.1"}, nil
+}
+
+func TestDNSCache(t *testing.T) {
+	var res fakeResolver
+	now := time.Unix(0, 0)
+	c := newDNSCache(time.Minute)
+	c.resolver = &res
+	c.now = func() time.Time { return now }
+	if c.dialer.Timeout <= 0 || c.dialer.KeepAlive <= 0 {
+		t.Errorf("Dialer has timeout %v and keep-alive %v; want positive values",
+			c.dialer.Timeout, c.dialer.KeepAlive)
+	}
+
+	for _, tc := range []struct {
+		host    string
+		advance time.Duration // added to now before lookup
+		lookups int           // expected total lookups
+	}{
+		{"example.org", 0, 1},
+		{"example.org", 30 * time.Second, 1},
+		{"example.com", 0, 2},
+		{"example.org", 31 * time.Second, 3}, // expired
+		{"example.org", 0, 3},
+	} {
+		now = now.Add(tc.advance)
+		if _, err := c.lookup(context.Background(), tc.host); err != nil {
+			t.Fatalf("lookup(%q) failed: %v", tc.host, err)
+		}
+		if res.lookups != tc.lookups {
+			t.Errorf("After looking up %q at %v, got %d lookup(s); want %d",
+				tc.host, now.Unix(), res.lookups, tc.lookups)
+		}
+	}
+}
+
+func TestDNSCache_Dial(t *testing.T) {
+	const (
+		v6a = "[2001:db8::1]:443"
+		v6b = "[2001:db8::2]:443"
+		v4  = "192.0.2.1:443"
+	)
+	for _, tc := range []struct {
+		name    string
+		addrs   []string
+		network string
+		delay   time.Duration     // dnsCache.dialer.FallbackDelay
+		dial    map[string]string // "ok", "fail", or "hang" for each address
+		want    string            // address that should be connected to ("" for error)
+	}{
+		{
+			name:  "serial",
+			addrs: []string{"2001:db8::1", "2001:db8::2"},
+			dial:  map[string]string{v6a: "fail", v6b: "ok"},
+			want:  v6b,
+		},
+		{
+			name:  "fallback after delay",
+			addrs: []string{"2001:db8::1", "192.0.2.1"},
+			delay: time.Millisecond,
+			dial:  map[string]string{v6a: "hang", v4: "ok"},
+			want:  v4,
+		},
+		{
+			name:  "fallback after failure",
+			addrs: []string{"2001:db8::1", "192.0.2.1"},
+			delay: time.Hour,
+			dial:  map[string]string{v6a: "fail", v4: "ok"},
+			want:  v4,
+		},
+		{
+			name:  "primary wins",
+			addrs: []string{"192.0.2.1", "2001:db8::1"},
+			delay: time.Hour,
+			dial:  map[string]string{v4: "ok", v6a: "ok"},
+			want:  v4,
+		},
+		{
+			name:  "all fail",
+			addrs: []string{"2001:db8::1", "192.0.2.1"},
+			delay: time.Millisecond,
+			dial:  map[string]string{v6a: "fail", v4: "fail"},
+		},
+		{
+			name:    "tcp4",
+			addrs:   []string{"2001:db8::1", "192.0.2.1"},
+			network: "tcp4",
+			delay:   time.Hour,
+			dial:    map[string]string{v6a: "ok", v4: "ok"},
+			want:    v4,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newDNSCache(time.Minute)
+			c.resolver = &fakeResolver{addrs: tc.addrs}
+			c.dialer.FallbackDelay = tc.delay
+			c.dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				switch tc.dial[addr] {
+				case "ok":
+					client, server := net.Pipe()
+					server.Close()
+					return &fakeConn{client, addr}, nil
+				case "hang":
+					<-ctx.Done()
+					return nil, ctx.Err()
+				default:
+					return nil, fmt.Errorf("dial %v failed", addr)
+				}
+			}
+			network := tc.network
+			if network == "" {
+				network = "tcp"
+			}
+			conn, err := c.dialContext(context.Background(), network, "example.org:443")
+			if tc.want == "" {
+				if err == nil {
+					t.Errorf("dialContext unexpectedly connected to %v", conn.(*fakeConn).addr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("dialContext failed: ", err)
+			}
+			defer conn.Close()
+			if got := conn.(*fakeConn).addr; got != tc.want {
+				t.Errorf("dialContext connected to %v; want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// fakeConn wraps a net.Conn and records the address that was dialed.
+type fakeConn struct {
+	net.Conn
+	addr string
+}
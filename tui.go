@@ -0,0 +1,376 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+const (
+	// Escape sequences used by runInteractive.
+	tuiStart = "\x1b[?1049h\x1b[?25l\x1b[?1000h\x1b[?1006h" // alt screen, hide cursor, SGR mouse
+	tuiEnd   = "\x1b[?1006l\x1b[?1000l\x1b[?25h\x1b[?1049l"
+	tuiClear = "\x1b[H\x1b[2J"
+	tuiRev   = "\x1b[7m" // reverse video
+	tuiReset = "\x1b[0m"
+
+	tuiSpacing = 2 // spaces between summary columns
+)
+
+const tuiSummaryHelp = "↑/↓ select  ←/→ or click sort column  r reverse  enter audits  q quit"
+const tuiDetailHelp = "↑/↓ scroll  esc back  q quit"
+
+// isTerminal returns true if f is a terminal.
+func isTerminal(f *os.File) bool { return term.IsTerminal(int(f.Fd())) }
+
+// runInteractive displays an interactive summary of reps on out (which should be a terminal),
+// reading keyboard and mouse input from in. It returns when the user quits.
+func runInteractive(in, out *os.File, reps []*report, cfg *reportConfig) error {
+	cfg = withoutColor(cfg) // lines are truncated by rune count
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return err
+	}
+	defer term.Restore(int(in.Fd()), state)
+	fmt.Fprint(out, tuiStart)
+	defer fmt.Fprint(out, tuiEnd)
+
+	m := newTUIModel(reps, cfg)
+	buf := make([]byte, 256)
+	for !m.quit {
+		width, height, err := term.GetSize(int(out.Fd()))
+		if err != nil {
+			width, height = 80, 24
+		}
+		fmt.Fprint(out, tuiClear+strings.Join(m.view(width, height), "\r\n"))
+		n, err := in.Read(buf)
+		if err != nil {
+			return err
+		}
+		for _, ev := range parseTUIInput(buf[:n]) {
+			m.update(ev)
+		}
+	}
+	return nil
+}
+
+// tuiEvent describes a key press or mouse click.
+type tuiEvent struct {
+	key  string // e.g. "up", "enter", "q", or "click"
+	x, y int    // 0-based cell for "click"
+}
+
+// tuiKeys maps from input sequences to tuiEvent keys.
+var tuiKeys = map[string]string{
+	"\x1b[A":  "up",
+	"\x1b[B":  "down",
+	"\x1b[C":  "right",
+	"\x1b[D":  "left",
+	"\x1b[5~": "pgup",
+	"\x1b[6~": "pgdown",
+	"\x1bOA":  "up",
+	"\x1bOB":  "down",
+	"\x1bOC":  "right",
+	"\x1bOD":  "left",
+	"\r":      "enter",
+	"\n":      "enter",
+	"\x1b":    "esc",
+	"\x03":    "q", // Ctrl-C
+}
+
+// tuiMouseRegexp matches an SGR mouse report, e.g. "\x1b[<0;12;1M" for a left-button
+// press at column 12 and row 1.
+var tuiMouseRegexp = regexp.MustCompile(`^\x1b\[<(\d+);(\d+);(\d+)([Mm])`)
+
+// parseTUIInput parses terminal input into events.
+// Unrecognized escape sequences are ignored.
+func parseTUIInput(b []byte) []tuiEvent {
+	var evs []tuiEvent
+	for len(b) > 0 {
+		if ms := tuiMouseRegexp.FindSubmatch(b); ms != nil {
+			btn, _ := strconv.Atoi(string(ms[1]))
+			x, _ := strconv.Atoi(string(ms[2]))
+			y, _ := strconv.Atoi(string(ms[3]))
+			if btn == 0 && string(ms[4]) == "M" {
+				evs = append(evs, tuiEvent{key: "click", x: x - 1, y: y - 1})
+			}
+			b = b[len(ms[0]):]
+			continue
+		}
+		if b[0] == 0x1b && len(b) > 1 {
+			matched := false
+			for _, n := range []int{4, 3} {
+				if len(b) >= n {
+					if key, ok := tuiKeys[string(b[:n])]; ok {
+						evs = append(evs, tuiEvent{key: key})
+						b = b[n:]
+						matched = true
+						break
+					}
+				}
+			}
+			if !matched {
+				// Skip an unknown escape sequence through its final byte.
+				end := bytes.IndexFunc(b[2:], func(r rune) bool { return r >= 0x40 && r <= 0x7e })
+				if end < 0 {
+					break
+				}
+				b = b[end+3:]
+			}
+			continue
+		}
+		r, n := utf8.DecodeRune(b)
+		if key, ok := tuiKeys[string(r)]; ok {
+			evs = append(evs, tuiEvent{key: key})
+		} else {
+			evs = append(evs, tuiEvent{key: string(r)})
+		}
+		b = b[n:]
+	}
+	return evs
+}
+
+// tuiModel holds the state of the interactive summary displayed by runInteractive.
+type tuiModel struct {
+	reps     []*report
+	cats     []category
+	cfg      *reportConfig
+	sortCol  int // 0 for URL or i+1 for cats[i]
+	sortDesc bool
+	cursor   int      // index of selected report in reps
+	top      int      // index of first visible row or detail line
+	rows     int      // number of rows or lines visible in the last view
+	starts   []int    // starting cell of each summary column in the last view
+	detail   []string // lines describing the selected report, or nil if showing the summary
+	quit     bool
+}
+
+func newTUIModel(reps []*report, cfg *reportConfig) *tuiModel {
+	return &tuiModel{
+		reps: append([]*report(nil), reps...),
+		cats: summaryCategories(reps),
+		cfg:  cfg,
+	}
+}
+
+// update updates m in response to ev.
+func (m *tuiModel) update(ev tuiEvent) {
+	if ev.key == "q" {
+		m.quit = true
+		return
+	}
+
+	if m.detail != nil {
+		switch ev.key {
+		case "up":
+			m.top--
+		case "down":
+			m.top++
+		case "pgup":
+			m.top -= m.rows
+		case "pgdown":
+			m.top += m.rows
+		case "esc", "left":
+			m.detail = nil
+			m.top = 0
+			return
+		}
+		if max := len(m.detail) - m.rows; m.top > max {
+			m.top = max
+		}
+		if m.top < 0 {
+			m.top = 0
+		}
+		return
+	}
+
+	switch ev.key {
+	case "up":
+		m.cursor--
+	case "down":
+		m.cursor++
+	case "pgup":
+		m.cursor -= m.rows
+	case "pgdown":
+		m.cursor += m.rows
+	case "left":
+		m.sortBy((m.sortCol+len(m.cats))%(len(m.cats)+1), m.sortDesc)
+	case "right":
+		m.sortBy((m.sortCol+1)%(len(m.cats)+1), m.sortDesc)
+	case "r":
+		m.sortBy(m.sortCol, !m.sortDesc)
+	case "enter":
+		if len(m.reps) > 0 {
+			var b bytes.Buffer
+			writeReport(&b, m.reps[m.cursor], m.cfg)
+			m.detail = strings.Split(strings.TrimRight(b.String(), "\n"), "\n")
+			m.top = 0
+		}
+	case "click":
+		if ev.y == 0 {
+			for i := len(m.starts) - 1; i >= 0; i-- {
+				if ev.x >= m.starts[i] {
+					m.sortBy(i, i == m.sortCol && !m.sortDesc)
+					break
+				}
+			}
+		} else if idx := m.top + ev.y - 1; idx < len(m.reps) {
+			m.cursor = idx
+		}
+	}
+	if m.cursor >= len(m.reps) {
+		m.cursor = len(m.reps) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+// sortBy stably sorts m.reps by the supplied column (see tuiModel.sortCol),
+// keeping the same report selected.
+func (m *tuiModel) sortBy(col int, desc bool) {
+	var sel *report
+	if len(m.reps) > 0 {
+		sel = m.reps[m.cursor]
+	}
+	m.sortCol, m.sortDesc = col, desc
+	sort.SliceStable(m.reps, func(i, j int) bool {
+		a, b := m.reps[i], m.reps[j]
+		if desc {
+			a, b = b, a
+		}
+		if col == 0 {
+			return a.URL < b.URL
+		}
+		return m.catScore(a, col-1) < m.catScore(b, col-1)
+	})
+	for i, rep := range m.reps {
+		if rep == sel {
+			m.cursor = i
+		}
+	}
+}
+
+// catScore returns rep's score for m.cats[idx], or -1 if it's missing.
+func (m *tuiModel) catScore(rep *report, idx int) float64 {
+	if cat := findCategory(rep, m.cats[idx].ID); cat != nil && cat.Score >= 0 {
+		if cat.ScoreFloat >= 0 {
+			return cat.ScoreFloat
+		}
+		return float64(cat.Score) / 100
+	}
+	return -1
+}
+
+// view returns the lines to display on a terminal with the supplied dimensions.
+func (m *tuiModel) view(width, height int) []string {
+	m.rows = height - 1 // leave room for help
+	if m.rows < 1 {
+		m.rows = 1
+	}
+	var lines []string
+	if m.detail != nil {
+		end := m.top + m.rows
+		if end > len(m.detail) {
+			end = len(m.detail)
+		}
+		for _, ln := range m.detail[m.top:end] {
+			lines = append(lines, truncateLine(ln, width))
+		}
+		return append(lines, truncateLine(tuiDetailHelp, width))
+	}
+
+	// Make the header consume a row and scroll to keep the selected report visible.
+	m.rows--
+	if m.rows < 1 {
+		m.rows = 1
+	}
+	if m.cursor < m.top {
+		m.top = m.cursor
+	} else if m.cursor >= m.top+m.rows {
+		m.top = m.cursor - m.rows + 1
+	}
+
+	rows := [][]string{{"URL"}}
+	opts := []tableOpt{tableSpacing(tuiSpacing)}
+	for i, cat := range m.cats {
+		rows[0] = append(rows[0], cat.Abbrev)
+		opts = append(opts, tableRightCol(i+1))
+	}
+	arrow := "▲"
+	if m.sortDesc {
+		arrow = "▼"
+	}
+	rows[0][m.sortCol] += arrow
+	for _, rep := range m.reps {
+		row := []string{displayURL(rep.URL, m.cfg)}
+		for _, cat := range m.cats {
+			var val string
+			if c := findCategory(rep, cat.ID); c != nil {
+				val = formatScore(c.Score, c.ScoreFloat, m.cfg)
+			} else if len(rep.Categories) == 0 {
+				val = "ERR"
+			}
+			row = append(row, val)
+		}
+		rows = append(rows, row)
+	}
+	m.starts = columnStarts(rows, tuiSpacing)
+
+	table := formatTable(rows, opts...)
+	lines = append(lines, truncateLine(table[0], width))
+	end := m.top + m.rows
+	if end > len(m.reps) {
+		end = len(m.reps)
+	}
+	for i := m.top; i < end; i++ {
+		ln := truncateLine(table[i+1], width)
+		if i == m.cursor {
+			ln = tuiRev + ln + strings.Repeat(" ", width-utf8.RuneCountInString(ln)) + tuiReset
+		}
+		lines = append(lines, ln)
+	}
+	for len(lines) <= m.rows {
+		lines = append(lines, "")
+	}
+	return append(lines, truncateLine(tuiSummaryHelp, width))
+}
+
+// columnStarts returns the starting cell of each column when rows are formatted
+// by formatTable with the supplied spacing.
+func columnStarts(rows [][]string, spacing int) []int {
+	var widths []int
+	for _, row := range rows {
+		for j, val := range row {
+			if j >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if w := utf8.RuneCountInString(val); w > widths[j] {
+				widths[j] = w
+			}
+		}
+	}
+	starts := make([]int, len(widths))
+	for j := 1; j < len(widths); j++ {
+		starts[j] = starts[j-1] + widths[j-1] + spacing
+	}
+	return starts
+}
+
+// truncateLine truncates ln to at most width runes.
+func truncateLine(ln string, width int) string {
+	if utf8.RuneCountInString(ln) <= width {
+		return ln
+	}
+	return string([]rune(ln)[:width])
+}
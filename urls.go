@@ -0,0 +1,54 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+)
+
+// readURLsFile reads URLs from the file at p (or stdin if p is "-").
+// See readURLs for the format.
+func readURLsFile(p string) ([]string, error) {
+	if p == "-" {
+		return readURLs(os.Stdin)
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readURLs(f)
+}
+
+// readURLs reads one URL per line from r.
+// Leading and trailing whitespace is trimmed, and blank lines and lines
+// starting with '#' are ignored.
+func readURLs(r io.Reader) ([]string, error) {
+	var urls []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		ln := strings.TrimSpace(sc.Text())
+		if ln == "" || strings.HasPrefix(ln, "#") {
+			continue
+		}
+		urls = append(urls, ln)
+	}
+	return urls, sc.Err()
+}
+
+// dedupeURLs returns urls with later duplicates removed.
+func dedupeURLs(urls []string) []string {
+	seen := make(map[string]struct{}, len(urls))
+	var deduped []string
+	for _, u := range urls {
+		if _, ok := seen[u]; !ok {
+			seen[u] = struct{}{}
+			deduped = append(deduped, u)
+		}
+	}
+	return deduped
+}
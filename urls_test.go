@@ -0,0 +1,37 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadURLs(t *testing.T) {
+	in := strings.Join([]string{
+		"# Main pages",
+		"https://example.org/",
+		"",
+		"  https://example.org/about  ",
+		"\t# indented comment",
+		"https://example.org/blog/",
+	}, "\n")
+	got, err := readURLs(strings.NewReader(in))
+	if err != nil {
+		t.Fatal("readURLs failed: ", err)
+	}
+	want := []string{"https://example.org/", "https://example.org/about", "https://example.org/blog/"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readURLs(%q) = %q; want %q", in, got, want)
+	}
+}
+
+func TestDedupeURLs(t *testing.T) {
+	in := []string{"https://a.org/", "https://b.org/", "https://a.org/", "https://c.org/", "https://b.org/"}
+	want := []string{"https://a.org/", "https://b.org/", "https://c.org/"}
+	if got := dedupeURLs(in); !reflect.DeepEqual(got, want) {
+		t.Errorf("dedupeURLs(%q) = %q; want %q", in, got, want)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// variant describes a version of each URL with additional query parameters,
+// e.g. to check how a page behaves after cookie consent has been given.
+type variant struct {
+	name   string // e.g. "with-consent"
+	params url.Values
+}
+
+// variantList implements flag.Value for the repeatable -variant flag.
+type variantList []variant
+
+func (vl *variantList) String() string {
+	var strs []string
+	for _, v := range *vl {
+		strs = append(strs, v.name+"="+v.params.Encode())
+	}
+	return strings.Join(strs, " ")
+}
+
+// Set parses a value like "with-consent=consent=1&tracking=0".
+func (vl *variantList) Set(s string) error {
+	i := strings.IndexByte(s, '=')
+	if i <= 0 {
+		return fmt.Errorf("%q isn't name=params", s)
+	}
+	name := s[:i]
+	params, err := url.ParseQuery(s[i+1:])
+	if err != nil {
+		return err
+	}
+	if len(params) == 0 {
+		return fmt.Errorf("no params for %q", name)
+	}
+	for k, vals := range params {
+		for _, v := range vals {
+			if v == "" {
+				// setQueryParam would just remove the param.
+				return fmt.Errorf("empty value for %q in %q", k, name)
+			}
+		}
+	}
+	for _, v := range *vl {
+		if v.name == name {
+			return fmt.Errorf("duplicate variant %q", name)
+		}
+	}
+	*vl = append(*vl, variant{name, params})
+	return nil
+}
+
+// expandVariants returns urls with each URL followed by its variants.
+// The returned map contains the variant names of the added URLs.
+// Duplicate URLs (e.g. a variant that was also passed directly) are omitted,
+// since URLs are used to identify jobs.
+func expandVariants(urls []string, variants []variant) (expanded []string, names map[string]string) {
+	names = make(map[string]string)
+	seen := make(map[string]struct{})
+	add := func(u, name string) {
+		if _, ok := seen[u]; ok {
+			return
+		}
+		seen[u] = struct{}{}
+		expanded = append(expanded, u)
+		if name != "" {
+			names[u] = name
+		}
+	}
+	for _, u := range urls {
+		add(u, "")
+		for _, v := range variants {
+			vu := u
+			keys := make([]string, 0, len(v.params))
+			for k := range v.params {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				vu = setQueryParam(vu, k, v.params.Get(k))
+			}
+			add(vu, v.name)
+		}
+	}
+	return expanded, names
+}
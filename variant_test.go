@@ -0,0 +1,51 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandVariants(t *testing.T) {
+	var vl variantList
+	for _, s := range []string{"with-consent=consent=1", "no-ads=ads=0&debug=1"} {
+		if err := vl.Set(s); err != nil {
+			t.Fatalf("Set(%q) failed: %v", s, err)
+		}
+	}
+	for _, s := range []string{"", "=consent=1", "bare", "with-consent=other=1", "empty=consent="} {
+		if err := vl.Set(s); err == nil {
+			t.Errorf("Set(%q) unexpectedly succeeded", s)
+		}
+	}
+
+	urls, names := expandVariants([]string{
+		"https://example.org/",
+		"https://example.org/a?x=1",
+		"https://example.org/?consent=1", // duplicates a variant
+	}, vl)
+	wantURLs := []string{
+		"https://example.org/",
+		"https://example.org/?consent=1",
+		"https://example.org/?ads=0&debug=1",
+		"https://example.org/a?x=1",
+		"https://example.org/a?x=1&consent=1",
+		"https://example.org/a?x=1&ads=0&debug=1",
+		"https://example.org/?consent=1&ads=0&debug=1",
+	}
+	if !reflect.DeepEqual(urls, wantURLs) {
+		t.Errorf("expandVariants returned URLs %q; want %q", urls, wantURLs)
+	}
+	wantNames := map[string]string{
+		"https://example.org/?consent=1":               "with-consent",
+		"https://example.org/?ads=0&debug=1":           "no-ads",
+		"https://example.org/a?x=1&consent=1":          "with-consent",
+		"https://example.org/a?x=1&ads=0&debug=1":      "no-ads",
+		"https://example.org/?consent=1&ads=0&debug=1": "no-ads",
+	}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("expandVariants returned names %q; want %q", names, wantNames)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// readWeights reads a JSON file at p mapping from audit IDs to weights,
+// e.g. {"largest-contentful-paint": 50, "cumulative-layout-shift": 0}.
+func readWeights(p string) (map[string]float64, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	var weights map[string]float64
+	if err := json.Unmarshal(b, &weights); err != nil {
+		return nil, err
+	}
+	for id, w := range weights {
+		if w < 0 {
+			return nil, fmt.Errorf("negative weight %v for %q", w, id)
+		}
+	}
+	return weights, nil
+}
+
+// weightedScore returns cat's score recomputed as the weighted mean of its audits'
+// scores, using the weights in overrides in place of the audits' own weights.
+// Audits without scores are skipped. -1 is returned if the total weight is zero.
+func weightedScore(cat *category, overrides map[string]float64) float64 {
+	var sum, total float64
+	for _, aud := range cat.Audits {
+		if aud.Score < 0 {
+			continue
+		}
+		w := aud.Weight
+		if o, ok := overrides[aud.ID]; ok {
+			w = o
+		}
+		score := aud.ScoreFloat
+		if score < 0 {
+			score = float64(aud.Score) / 100
+		}
+		sum += score * w
+		total += w
+	}
+	if total == 0 {
+		return -1
+	}
+	return sum / total
+}
+
+// applyWeights replaces the scores of categories in rep containing audits
+// listed in overrides with scores computed by weightedScore.
+func applyWeights(rep *report, overrides map[string]float64) {
+	for i := range rep.Categories {
+		cat := &rep.Categories[i]
+		affected := false
+		for _, aud := range cat.Audits {
+			if _, ok := overrides[aud.ID]; ok {
+				affected = true
+				break
+			}
+		}
+		if !affected {
+			continue
+		}
+		if s := weightedScore(cat, overrides); s >= 0 {
+			cat.ScoreFloat = s
+			cat.Score = int(math.Round(s * 100))
+		} else {
+			cat.ScoreFloat, cat.Score = -1, -1
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2022 Daniel Erat.
+// All rights reserved.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadWeights(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "weights.json")
+	if err := os.WriteFile(p, []byte(`{"largest-contentful-paint": 50, "cumulative-layout-shift": 0}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]float64{"largest-contentful-paint": 50, "cumulative-layout-shift": 0}
+	if got, err := readWeights(p); err != nil {
+		t.Errorf("readWeights(%q) failed: %v", p, err)
+	} else if !reflect.DeepEqual(got, want) {
+		t.Errorf("readWeights(%q) = %v; want %v", p, got, want)
+	}
+
+	for _, data := range []string{`{"speed-index": -1}`, `["speed-index"]`, `{`} {
+		if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readWeights(p); err == nil {
+			t.Errorf("readWeights with %s unexpectedly succeeded", data)
+		}
+	}
+}
+
+func TestApplyWeights(t *testing.T) {
+	rep := &report{Categories: []category{
+		{ID: "performance", Score: 53, ScoreFloat: 0.53, Audits: []audit{
+			{ID: "largest-contentful-paint", Score: 20, ScoreFloat: 0.2, Weight: 25},
+			{ID: "total-blocking-time", Score: 80, ScoreFloat: 0.8, Weight: 30},
+			{ID: "cumulative-layout-shift", Score: 100, ScoreFloat: 1, Weight: 25},
+			{ID: "speed-index", Score: 50, ScoreFloat: -1, Weight: 10},
+			{ID: "diagnostics", Score: -1, ScoreFloat: -1, Weight: 10},
+			{ID: "unused-javascript", Score: 0, ScoreFloat: 0},
+		}},
+		{ID: "seo", Score: 90, ScoreFloat: 0.9, Audits: []audit{
+			{ID: "document-title", Score: 0, ScoreFloat: 0, Weight: 1},
+		}},
+	}}
+	applyWeights(rep, map[string]float64{"largest-contentful-paint": 50, "cumulative-layout-shift": 0})
+
+	// (0.2*50 + 0.8*30 + 0.5*10) / 90 = 0.433...
+	if got, want := rep.Categories[0].Score, 43; got != want {
+		t.Errorf("Performance score = %v; want %v", got, want)
+	}
+	// Categories without overridden audits should be unchanged.
+	if got, want := rep.Categories[1].Score, 90; got != want {
+		t.Errorf("SEO score = %v; want %v", got, want)
+	}
+
+	// Zeroing all weights leaves the category unscored.
+	cat := category{Audits: []audit{{ID: "speed-index", Score: 50, ScoreFloat: 0.5, Weight: 10}}}
+	if got := weightedScore(&cat, map[string]float64{"speed-index": 0}); got != -1 {
+		t.Errorf("weightedScore with zero weights = %v; want -1", got)
+	}
+}